@@ -5,8 +5,12 @@ import (
 	"actors-service/internal/postgres"
 	"actors-service/internal/repository"
 	"actors-service/internal/service"
+	"actors-service/pkg/health"
+	"actors-service/pkg/logger"
+	"actors-service/pkg/metrics"
+	"actors-service/pkg/tracing"
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,56 +19,72 @@ import (
 )
 
 func Run() error {
+	logger.Setup("actors-service")
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "actors-service")
+	if err != nil {
+		return err
+	}
+
+	defer shutdownTracing(context.Background())
+
 	router := http.NewServeMux()
 	db, err := postgres.NewConnectDb()
 	if err != nil {
-		log.Panicln(err)
+		return err
 	}
 
-	log.Println("Postgres DB connected: ", db)
+	slog.Info("Postgres DB connected")
+
+	metrics.RegisterDB(db.DB, "postgres")
 
 	actorRepo := repository.NewActorRepository(db)
 	actorService := service.NewActorService(actorRepo)
 
 	handler.NewActorHandler(router, actorService)
 
-	log.Println("Actor repository initialized:", actorRepo)
-	log.Println("Actor service initialized:", actorService)
+	health.Register(router, map[string]health.Check{"postgres": db.PingContext})
 
 	server := http.Server{
 		Addr:    ":8003",
-		Handler: router,
+		Handler: metrics.Instrument(router, logger.AccessLog(router, tracing.Handler("actors-service", router))),
 	}
 
+	metricsServer := metrics.Serve(os.Getenv("METRICS_ADDR"))
+
 	quit := make(chan os.Signal, 1)
 
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		log.Println("Actors microservice start on port 8003")
+		slog.Info("Actors microservice started", "addr", server.Addr)
 		err := server.ListenAndServe()
 		if err != nil && err != http.ErrServerClosed {
-			log.Fatalf("ListenAndServe error: %v", err)
+			slog.Error("ListenAndServe error", "error", err)
+			os.Exit(1)
 		}
 	}()
 
 	<-quit
-	log.Println("Shutting down actors microservice...")
+	slog.Info("Shutting down actors microservice")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	metricsServer.Shutdown(ctx)
+
 	err = server.Shutdown(ctx)
 	if err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		slog.Error("Server forced to shutdown", "error", err)
+		os.Exit(1)
 	}
 
 	err = db.Close()
 	if err != nil {
-		log.Printf("Error closing database: %v", err)
+		slog.Error("Error closing database", "error", err)
 	}
 
-	log.Println("Actor microservice stopped gracefully")
+	slog.Info("Actor microservice stopped gracefully")
 
 	return nil
 
@@ -73,6 +93,7 @@ func Run() error {
 func main() {
 	err := Run()
 	if err != nil {
-		log.Fatalf("Actor microservice failed: %v", err)
+		slog.Error("Actor microservice failed", "error", err)
+		os.Exit(1)
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"actors-service/internal/model"
 	"actors-service/internal/payload"
 	"actors-service/internal/repository"
+	"actors-service/pkg/metrics"
 	"context"
 )
 
@@ -22,6 +23,7 @@ func (s *ActorService) Create(ctx context.Context, p *payload.ActorPayload) (uin
 	if err != nil {
 		return 0, err
 	}
+	metrics.ActorsCreated.Inc()
 	return actorID, nil
 }
 
@@ -62,5 +64,6 @@ func (s *ActorService) Delete(ctx context.Context, id uint) error {
 	if err != nil {
 		return err
 	}
+	metrics.ActorsDeleted.Inc()
 	return nil
 }
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels requests no route matched, so random paths do not
+// create new series.
+const unmatchedRoute = "unmatched"
+
+var (
+	requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route pattern and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	ActorsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "actors_created_total",
+		Help: "Actors created.",
+	})
+
+	ActorsDeleted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "actors_deleted_total",
+		Help: "Actors deleted.",
+	})
+)
+
+// Routes finds the route pattern of a request, *http.ServeMux implements it.
+type Routes interface {
+	Handler(r *http.Request) (http.Handler, string)
+}
+
+// Instrument counts and times every request by its route pattern.
+func Instrument(routes Routes, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		_, route := routes.Handler(r)
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		status := strconv.Itoa(rec.status)
+
+		requests.WithLabelValues(r.Method, route, status).Inc()
+		requestDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+// DefaultAddr is where Serve listens when METRICS_ADDR is not set.
+const DefaultAddr = ":9090"
+
+// Serve starts the internal listener that serves GET /metrics on addr. It is
+// kept apart from the public port so the metrics are not reachable through
+// it. The returned server is shut down together with the main one.
+func Serve(addr string) *http.Server {
+	if addr == "" {
+		addr = DefaultAddr
+	}
+
+	router := http.NewServeMux()
+	router.Handle("GET /metrics", promhttp.Handler())
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           router,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		slog.Info("Metrics listener started", "addr", server.Addr)
+		err := server.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			slog.Error("Metrics listener error", "error", err)
+		}
+	}()
+
+	return server
+}
+
+// RegisterDB exports the connection pool stats of db.
+func RegisterDB(db *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+
+	// Informational answers such as 103 Early Hints precede the real one.
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		r.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	r.wroteHeader = true
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach Flush of the real writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -1,113 +1,183 @@
 package main
 
 import (
+	"api-gateway/internal/apikey"
+	"api-gateway/internal/compress"
+	"api-gateway/internal/jwks"
+	"api-gateway/internal/postgres"
+	"api-gateway/internal/ratelimit"
+	"api-gateway/internal/revocation"
+	"api-gateway/internal/routes"
 	"api-gateway/middleware"
+	"api-gateway/pkg/health"
+	"api-gateway/pkg/logger"
+	"api-gateway/pkg/metrics"
+	"api-gateway/pkg/tracing"
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 )
 
-func proxyToService(target string, prefix string) http.Handler {
-	return http.StripPrefix(prefix, httputil.NewSingleHostReverseProxy(&url.URL{
-		Scheme: "http",
-		Host:   target,
-	}))
-}
+// /api/health публичный: ответ кешируется, а частота запросов с одного адреса
+// ограничена, чтобы через него нельзя было нагрузить upstream-сервисы
+const healthCacheTTL = 5 * time.Second
+
+var healthLimit = ratelimit.Limit{Requests: 30, Per: time.Minute, Burst: 10}
 
 func main() {
-	os.Setenv("JWT_SECRET", os.Getenv("JWT_SECRET"))
+	logger.Setup("api-gateway")
 
-	// Регистрация и авторизация
+	shutdownTracing, err := tracing.Setup(context.Background(), "api-gateway")
+	if err != nil {
+		slog.Error("Tracing setup error", "error", err)
+		os.Exit(1)
+	}
 
-	http.Handle("/api/auth/", proxyToService("auth:8001", "/api/auth"))
+	defer shutdownTracing(context.Background())
 
-	// actors service для пользователя
+	db, err := postgres.NewConnectDb()
+	if err != nil {
+		slog.Error("Postgres connection error", "error", err)
+		os.Exit(1)
+	}
 
-	http.Handle("/api/actors", middleware.CheckRoleAndMethod(
-		"user",
-		[]string{"GET"},
-		proxyToService("actors:8003", "/api"),
-	))
-	http.Handle("/api/actors/", middleware.CheckRoleAndMethod(
-		"user",
-		[]string{"GET"},
-		proxyToService("actors:8003", "/api"),
-	))
+	defer db.Close()
 
-	// actors service для админа
+	metrics.RegisterDB(db.DB, "postgres")
 
-	http.Handle("/api/admin/actors", middleware.CheckRoleAndMethod(
-		"admin",
-		[]string{"GET", "POST", "PUT", "PATCH", "DELETE"},
-		proxyToService("actors:8003", "/api/admin"),
-	))
+	cacheTTL, err := time.ParseDuration(os.Getenv("REVOCATION_CACHE_TTL"))
+	if err != nil {
+		cacheTTL = 30 * time.Second
+	}
 
-	http.Handle("/api/admin/actors/", middleware.CheckRoleAndMethod(
-		"admin",
-		[]string{"GET", "POST", "PUT", "PATCH", "DELETE"},
-		proxyToService("actors:8003", "/api/admin"),
-	))
+	jwksURL := os.Getenv("JWKS_URL")
+	if jwksURL == "" {
+		jwksURL = "http://auth:8001/.well-known/jwks.json"
+	}
 
-	// movies service для пользователя
+	keys := jwks.NewCache(jwksURL)
 
-	http.Handle("/api/movies", middleware.CheckRoleAndMethod(
-		"user",
-		[]string{"GET"},
-		proxyToService("movies:8002", "/api"),
-	))
+	err = keys.Refresh(context.Background())
+	if err != nil {
+		slog.Warn("Initial JWKS fetch failed, will retry", "error", err)
+	}
 
-	http.Handle("/api/movies/", middleware.CheckRoleAndMethod(
-		"user",
-		[]string{"GET"},
-		proxyToService("movies:8002", "/api"),
-	))
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	go keys.Run(backgroundCtx, 5*time.Minute)
+
+	auth := middleware.NewAuthMiddleware(
+		keys,
+		revocation.NewStore(db, cacheTTL),
+		apikey.NewStore(db, cacheTTL),
+	)
+
+	// Ограничение частоты запросов
+
+	var rateLimitStore ratelimit.Store
+
+	switch backend := os.Getenv("RATE_LIMIT_BACKEND"); backend {
+	case "", "memory":
+		store := ratelimit.NewMemoryStore()
+		go store.Run(backgroundCtx, time.Minute)
+		rateLimitStore = store
+	case "postgres":
+		store := ratelimit.NewPostgresStore(db)
+		go store.Run(backgroundCtx, time.Minute)
+		rateLimitStore = store
+	default:
+		slog.Error("Unknown RATE_LIMIT_BACKEND", "backend", backend)
+		os.Exit(1)
+	}
 
-	// movies service для админа
+	// Таблица маршрутов
 
-	http.Handle("/api/admin/movies", middleware.CheckRoleAndMethod(
-		"admin",
-		[]string{"GET", "POST", "PUT", "PATCH", "DELETE"},
-		proxyToService("movies:8002", "/api/admin"),
-	))
+	routesPath := os.Getenv("ROUTES_CONFIG")
+	if routesPath == "" {
+		routesPath = "config/routes.yaml"
+	}
+
+	limiter := ratelimit.NewLimiter(rateLimitStore)
+
+	router, err := routes.NewRouter(routesPath, auth, limiter)
+	if err != nil {
+		slog.Error("Route table error", "error", err)
+		os.Exit(1)
+	}
+
+	routesWatchInterval, err := time.ParseDuration(os.Getenv("ROUTES_WATCH_INTERVAL"))
+	if err != nil {
+		routesWatchInterval = 5 * time.Second
+	}
+
+	go router.Watch(backgroundCtx, routesWatchInterval)
 
-	http.Handle("/api/admin/movies/", middleware.CheckRoleAndMethod(
-		"admin",
-		[]string{"GET", "POST", "PUT", "PATCH", "DELETE"},
-		proxyToService("movies:8002", "/api/admin"),
+	// Проверки состояния отдаются в обход таблицы маршрутов,
+	// метрики Prometheus — на отдельном внутреннем адресе METRICS_ADDR
+
+	checks := map[string]health.Check{"postgres": db.PingContext}
+
+	root := http.NewServeMux()
+	health.Register(root, checks)
+	root.Handle("GET /api/health", limiter.Handler("GET /api/health", healthLimit,
+		health.Aggregate(checks, router, http.DefaultClient, healthCacheTTL),
 	))
+	root.Handle("/", metrics.Instrument(router, logger.AccessLog(router,
+		middleware.StripIdentityHeaders(tracing.Handler("api-gateway", compress.Handler(router))),
+	)))
+
+	// Таймауты сервера: WriteTimeout должен быть больше самого долгого timeout маршрута
 
 	server := http.Server{
-		Addr: ":8080",
+		Addr:              ":8080",
+		Handler:           root,
+		ReadHeaderTimeout: durationEnv("SERVER_READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       durationEnv("SERVER_READ_TIMEOUT", 30*time.Second),
+		WriteTimeout:      durationEnv("SERVER_WRITE_TIMEOUT", time.Minute),
+		IdleTimeout:       durationEnv("SERVER_IDLE_TIMEOUT", 2*time.Minute),
 	}
 
+	metricsServer := metrics.Serve(os.Getenv("METRICS_ADDR"))
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		log.Println("API Gateway started on :8080")
+		slog.Info("API Gateway started", "addr", server.Addr)
 		err := server.ListenAndServe()
 		if err != nil && err != http.ErrServerClosed {
-			log.Fatalf("ListenAndServe error: %v", err)
+			slog.Error("ListenAndServe error", "error", err)
+			os.Exit(1)
 		}
 	}()
 
 	<-quit
-	log.Println("Shutting down API Gateway...")
+	slog.Info("Shutting down API Gateway")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	err := server.Shutdown(ctx)
+	metricsServer.Shutdown(ctx)
+
+	err = server.Shutdown(ctx)
 	if err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		slog.Error("Server forced to shutdown", "error", err)
+		os.Exit(1)
 	}
 
-	log.Println("API-gateway stopped gracefully")
+	slog.Info("API-gateway stopped gracefully")
 
 }
+
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,144 @@
+package apikey
+
+import (
+	"api-gateway/internal/postgres"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+const maxCacheEntries = 10000
+
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+var ErrFailedCheckAPIKey = errors.New("failed to check api key")
+
+// Identity is the owner of a valid API key as seen at lookup time.
+type Identity struct {
+	UserID      uint
+	Role        string
+	Permissions []string
+	Scopes      []string
+	ExpiresAt   *time.Time
+}
+
+func (i *Identity) HasScope(scope string) bool {
+	return slices.Contains(i.Scopes, scope)
+}
+
+type cacheEntry struct {
+	identity  *Identity
+	expiresAt time.Time
+}
+
+// Store resolves API keys against the auth database. Results, including
+// unknown keys, are cached for ttl, so revoking a key or disabling its owner
+// takes effect at most ttl later.
+type Store struct {
+	Database *postgres.Db
+	ttl      time.Duration
+
+	mu   sync.Mutex
+	keys map[string]cacheEntry
+}
+
+func NewStore(db *postgres.Db, ttl time.Duration) *Store {
+	return &Store{
+		Database: db,
+		ttl:      ttl,
+		keys:     make(map[string]cacheEntry),
+	}
+}
+
+// Lookup returns the identity behind key, or nil if the key is unknown,
+// revoked, expired or belongs to a disabled user.
+func (s *Store) Lookup(ctx context.Context, key string) (*Identity, error) {
+	sum := sha256.Sum256([]byte(key))
+	hash := hex.EncodeToString(sum[:])
+
+	s.mu.Lock()
+	entry, ok := s.keys[hash]
+	s.mu.Unlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		identity, err := s.load(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+
+		entry = cacheEntry{identity: identity, expiresAt: time.Now().Add(s.ttl)}
+
+		s.mu.Lock()
+		if len(s.keys) >= maxCacheEntries {
+			s.sweep()
+		}
+		s.keys[hash] = entry
+		s.mu.Unlock()
+	}
+
+	identity := entry.identity
+	if identity == nil || (identity.ExpiresAt != nil && time.Now().After(*identity.ExpiresAt)) {
+		return nil, nil
+	}
+
+	return identity, nil
+}
+
+func (s *Store) load(ctx context.Context, hash string) (*Identity, error) {
+	query, args, err := sq.
+		Select(
+			"k.user_id",
+			"u.role",
+			"ARRAY(SELECT permission FROM role_permissions rp WHERE rp.role = u.role)",
+			"k.scopes",
+			"k.expires_at",
+		).
+		From("api_keys k").
+		Join("users u ON u.id = k.user_id").
+		Where(sq.Eq{"k.key_hash": hash}).
+		Where(sq.Eq{"k.revoked_at": nil}).
+		Where(sq.Eq{"u.disabled_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, ErrFailedCheckAPIKey
+	}
+
+	var identity Identity
+
+	err = s.Database.DB.QueryRowContext(ctx, query, args...).Scan(
+		&identity.UserID,
+		&identity.Role,
+		pq.Array(&identity.Permissions),
+		pq.Array(&identity.Scopes),
+		&identity.ExpiresAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ErrFailedCheckAPIKey
+	}
+
+	return &identity, nil
+}
+
+func (s *Store) sweep() {
+	now := time.Now()
+	for k, entry := range s.keys {
+		if now.After(entry.expiresAt) {
+			delete(s.keys, k)
+		}
+	}
+}
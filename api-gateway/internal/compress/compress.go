@@ -0,0 +1,316 @@
+package compress
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// minSize is the smallest answer worth compressing, below it the encoding
+// overhead eats the gain.
+const minSize = 1024
+
+const (
+	encodingZstd = "zstd"
+	encodingGzip = "gzip"
+)
+
+// encodings are in the order of preference when the client accepts several
+// with the same weight.
+var encodings = []string{encodingZstd, encodingGzip}
+
+var compressibleTypes = map[string]bool{
+	"application/json":         true,
+	"application/problem+json": true,
+	"application/javascript":   true,
+	"application/xml":          true,
+	"text/html":                true,
+	"text/plain":               true,
+	"text/css":                 true,
+	"text/csv":                 true,
+	"text/xml":                 true,
+}
+
+var (
+	gzipWriters = sync.Pool{
+		New: func() any {
+			return gzip.NewWriter(io.Discard)
+		},
+	}
+
+	zstdWriters = sync.Pool{
+		New: func() any {
+			w, _ := zstd.NewWriter(io.Discard, zstd.WithEncoderConcurrency(1))
+			return w
+		},
+	}
+)
+
+// Handler compresses text and JSON answers of at least minSize bytes with
+// the encoding the client prefers in Accept-Encoding, zstd or gzip. Answers
+// the upstream already encoded are passed through unchanged.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := negotiate(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, status: http.StatusOK}
+		defer cw.close()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiate picks the encoding with the highest q-value, "*" standing for
+// any encoding not listed on its own. It returns "" if the client accepts
+// neither zstd nor gzip.
+func negotiate(header string) string {
+	weights := make(map[string]float64)
+	wildcard := -1.0
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(param, "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		weights[name] = q
+	}
+
+	best, bestQ := "", 0.0
+
+	for _, encoding := range encodings {
+		q, ok := weights[encoding]
+		if !ok {
+			q = wildcard
+		}
+
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+
+	return best
+}
+
+func compressible(header http.Header) bool {
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+
+	return compressibleTypes[mediaType]
+}
+
+// compressWriter holds the status and the first minSize bytes back until it
+// knows whether the answer is worth compressing.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	status   int
+
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	encoder     io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+
+	// Informational answers such as 103 Early Hints go out right away.
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	w.wroteHeader = true
+	w.status = status
+
+	if !bodyAllowed(status) || !compressible(w.Header()) {
+		w.decide(false)
+		return
+	}
+
+	// A declared length tells the size right away.
+	length, err := strconv.Atoi(w.Header().Get("Content-Length"))
+	if err == nil {
+		w.decide(length >= minSize)
+	}
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= minSize {
+		err := w.flushBuffer(true)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(b), nil
+}
+
+// Flush sends what is buffered, so streamed answers keep streaming. An answer
+// flushed before minSize bytes is compressed when its type allows it, its
+// final size being unknown.
+func (w *compressWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if !w.decided {
+		w.flushBuffer(bodyAllowed(w.status) && compressible(w.Header()))
+	}
+
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack lets protocol upgrades through the proxy, they are never compressed.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the deadlines of the real writer.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) flushBuffer(compress bool) error {
+	w.decide(compress)
+
+	buf := w.buf
+	w.buf = nil
+
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+
+	return err
+}
+
+// decide writes the real header, switching to the encoder if compress is set.
+func (w *compressWriter) decide(compress bool) {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	header := w.Header()
+
+	if bodyAllowed(w.status) && compressible(header) {
+		header.Add("Vary", "Accept-Encoding")
+	}
+
+	if compress {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+
+		// The compressed body differs byte for byte, a strong ETag would lie.
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+
+		w.encoder = newEncoder(w.encoding, w.ResponseWriter)
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+func (w *compressWriter) close() {
+	if !w.wroteHeader {
+		// Nothing was written, net/http sends the empty 200 itself.
+		return
+	}
+
+	if !w.decided {
+		w.flushBuffer(len(w.buf) >= minSize)
+	}
+
+	if w.encoder != nil {
+		w.encoder.Close()
+		putEncoder(w.encoding, w.encoder)
+		w.encoder = nil
+	}
+}
+
+func newEncoder(encoding string, dst io.Writer) io.WriteCloser {
+	switch encoding {
+	case encodingZstd:
+		encoder := zstdWriters.Get().(*zstd.Encoder)
+		encoder.Reset(dst)
+		return encoder
+	default:
+		encoder := gzipWriters.Get().(*gzip.Writer)
+		encoder.Reset(dst)
+		return encoder
+	}
+}
+
+func putEncoder(encoding string, encoder io.WriteCloser) {
+	switch encoding {
+	case encodingZstd:
+		zstdWriters.Put(encoder)
+	default:
+		gzipWriters.Put(encoder)
+	}
+}
+
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,179 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "gzip", want: "gzip"},
+		{header: "zstd", want: "zstd"},
+		{header: "gzip, zstd", want: "zstd"},
+		{header: "gzip, deflate, br, zstd", want: "zstd"},
+		{header: "zstd;q=0.5, gzip", want: "gzip"},
+		{header: "zstd;q=0.5, gzip;q=0.8", want: "gzip"},
+		{header: "gzip;q=0.2, zstd;q=0.3", want: "zstd"},
+		{header: "GZIP ; q=0.8", want: "gzip"},
+		{header: "zstd;q=0, gzip;q=0", want: ""},
+		{header: "zstd;q=0, gzip", want: "gzip"},
+		{header: "zstd;q=invalid", want: "zstd"},
+		{header: "br, deflate", want: ""},
+		{header: "*", want: "zstd"},
+		{header: "*;q=0.5, gzip", want: "gzip"},
+		{header: "*;q=0, gzip", want: "gzip"},
+		{header: "*;q=0", want: ""},
+		{header: "identity;q=0", want: ""},
+		{header: "identity;q=0, gzip", want: "gzip"},
+		{header: "identity, *;q=0", want: ""},
+	}
+
+	for _, tt := range tests {
+		got := negotiate(tt.header)
+		if got != tt.want {
+			t.Errorf("negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	small := strings.Repeat("a", minSize-1)
+	large := strings.Repeat("a", minSize)
+
+	tests := []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		header         http.Header
+		status         int
+		body           string
+		chunk          int
+		wantEncoding   string
+		wantETag       string
+	}{
+		{name: "below threshold", acceptEncoding: "gzip", body: small},
+		{name: "at threshold", acceptEncoding: "gzip", body: large, wantEncoding: "gzip"},
+		{name: "zstd preferred", acceptEncoding: "gzip, zstd", body: large, wantEncoding: "zstd"},
+		{name: "small writes reach threshold", acceptEncoding: "gzip", body: large, chunk: 100, wantEncoding: "gzip"},
+		{name: "declared length below threshold", acceptEncoding: "gzip", header: http.Header{"Content-Length": {strconv.Itoa(len(small))}}, body: small},
+		{name: "declared length at threshold", acceptEncoding: "zstd", header: http.Header{"Content-Length": {strconv.Itoa(len(large))}}, body: large, wantEncoding: "zstd"},
+		{name: "not accepted", acceptEncoding: "identity", body: large},
+		{name: "head", method: "HEAD", acceptEncoding: "gzip", body: ""},
+		{name: "binary type", acceptEncoding: "gzip", header: http.Header{"Content-Type": {"image/png"}}, body: large},
+		{name: "already encoded", acceptEncoding: "gzip", header: http.Header{"Content-Encoding": {"br"}}, body: large},
+		{name: "no content", acceptEncoding: "gzip", status: http.StatusNoContent},
+		{name: "strong etag becomes weak", acceptEncoding: "gzip", header: http.Header{"Etag": {`"v1"`}}, body: large, wantEncoding: "gzip", wantETag: `W/"v1"`},
+		{name: "weak etag stays", acceptEncoding: "gzip", header: http.Header{"Etag": {`W/"v1"`}}, body: large, wantEncoding: "gzip", wantETag: `W/"v1"`},
+		{name: "etag of uncompressed answer stays strong", acceptEncoding: "gzip", header: http.Header{"Etag": {`"v1"`}}, body: small, wantETag: `"v1"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				for key, values := range tt.header {
+					w.Header()[key] = values
+				}
+
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+
+				body := []byte(tt.body)
+				chunk := tt.chunk
+				if chunk == 0 {
+					chunk = max(len(body), 1)
+				}
+				for len(body) > 0 {
+					n := min(chunk, len(body))
+					w.Write(body[:n])
+					body = body[n:]
+				}
+			}))
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			req := httptest.NewRequest(method, "/api/movies", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			wantStatus := tt.status
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+			if rec.Code != wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, wantStatus)
+			}
+
+			encoding := rec.Header().Get("Content-Encoding")
+			if tt.header.Get("Content-Encoding") == "" && encoding != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", encoding, tt.wantEncoding)
+			}
+
+			if tt.wantEncoding != "" {
+				if rec.Header().Get("Content-Length") != "" {
+					t.Error("Content-Length kept on a compressed answer")
+				}
+				if !strings.Contains(rec.Header().Get("Vary"), "Accept-Encoding") {
+					t.Error("Vary: Accept-Encoding missing on a compressed answer")
+				}
+			}
+
+			if etag := rec.Header().Get("ETag"); etag != tt.wantETag {
+				t.Errorf("ETag = %q, want %q", etag, tt.wantETag)
+			}
+
+			got := decode(t, tt.wantEncoding, rec.Body.Bytes())
+			if got != tt.body {
+				t.Errorf("body is %d bytes, want %d", len(got), len(tt.body))
+			}
+		})
+	}
+}
+
+func decode(t *testing.T, encoding string, body []byte) string {
+	t.Helper()
+
+	var r io.Reader
+	switch encoding {
+	case encodingGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gr
+	case encodingZstd:
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return string(body)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
@@ -0,0 +1,216 @@
+package cors
+
+import (
+	"api-gateway/pkg/res"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const anyValue = "*"
+
+var ErrInvalidPolicy = errors.New("invalid CORS policy")
+
+var (
+	DefaultMethods = []string{
+		http.MethodGet,
+		http.MethodHead,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+	}
+	DefaultHeaders = []string{"Authorization", "Content-Type"}
+)
+
+var knownMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+	http.MethodOptions,
+}
+
+// Policy lets pages from AllowedOrigins call the API from a browser. "*" in
+// AllowedOrigins allows any origin, in AllowedHeaders any request header.
+// Empty AllowedMethods and AllowedHeaders mean DefaultMethods and
+// DefaultHeaders. ExposedHeaders are the response headers scripts may read,
+// MaxAge is how long browsers may cache a preflight answer.
+type Policy struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins"`
+	AllowedMethods   []string      `yaml:"allowed_methods"`
+	AllowedHeaders   []string      `yaml:"allowed_headers"`
+	ExposedHeaders   []string      `yaml:"exposed_headers"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age"`
+}
+
+func (p *Policy) Validate() error {
+	if len(p.AllowedOrigins) == 0 {
+		return fmt.Errorf("%w: allowed_origins is empty", ErrInvalidPolicy)
+	}
+
+	for _, origin := range p.AllowedOrigins {
+		if origin == anyValue {
+			if p.AllowCredentials {
+				return fmt.Errorf("%w: origin \"*\" cannot be combined with allow_credentials", ErrInvalidPolicy)
+			}
+			continue
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+			return fmt.Errorf("%w: origin %q must be scheme://host[:port]", ErrInvalidPolicy, origin)
+		}
+	}
+
+	for _, method := range p.AllowedMethods {
+		if !slices.Contains(knownMethods, method) {
+			return fmt.Errorf("%w: unknown method %q", ErrInvalidPolicy, method)
+		}
+	}
+
+	if p.MaxAge < 0 {
+		return fmt.Errorf("%w: max_age cannot be negative", ErrInvalidPolicy)
+	}
+
+	return nil
+}
+
+// Handler answers preflight requests itself, so they never reach
+// authentication or rate limiting, and adds the CORS headers to every other
+// request from an allowed origin, errors included. Requests from other
+// origins get no CORS headers and the browser keeps the answer from the page.
+func (p *Policy) Handler(next http.Handler) http.Handler {
+	origins := make(map[string]bool, len(p.AllowedOrigins))
+	for _, origin := range p.AllowedOrigins {
+		origins[strings.ToLower(origin)] = true
+	}
+	anyOrigin := origins[anyValue]
+
+	methods := p.AllowedMethods
+	if len(methods) == 0 {
+		methods = DefaultMethods
+	}
+
+	headers := p.AllowedHeaders
+	if len(headers) == 0 {
+		headers = DefaultHeaders
+	}
+
+	allowedHeaders := make(map[string]bool, len(headers))
+	for _, header := range headers {
+		allowedHeaders[strings.ToLower(header)] = true
+	}
+	anyHeader := allowedHeaders[anyValue]
+
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(headers, ", ")
+	exposeHeaders := strings.Join(p.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(p.MaxAge.Seconds()))
+
+	// Only "*" without credentials gives every origin the same answer.
+	varyOrigin := !anyOrigin || p.AllowCredentials
+
+	allowOrigin := func(w http.ResponseWriter, origin string) {
+		if anyOrigin && !p.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Origin", anyValue)
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if p.AllowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && (anyOrigin || origins[strings.ToLower(origin)])
+
+		if isPreflight(r) {
+			w.Header().Add("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+
+			if !allowed {
+				res.ErrResJson(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+
+			if !slices.Contains(methods, r.Header.Get("Access-Control-Request-Method")) {
+				res.ErrResJson(w, "method not allowed", http.StatusForbidden)
+				return
+			}
+
+			requested := requestedHeaders(r)
+			if !anyHeader {
+				for _, header := range requested {
+					if !allowedHeaders[header] {
+						res.ErrResJson(w, "header "+header+" not allowed", http.StatusForbidden)
+						return
+					}
+				}
+			}
+
+			allowOrigin(w, origin)
+			w.Header().Set("Access-Control-Allow-Methods", allowMethods)
+
+			if anyHeader {
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(requested, ", "))
+			} else {
+				w.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+			}
+
+			if p.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if varyOrigin {
+			w.Header().Add("Vary", "Origin")
+		}
+
+		if allowed {
+			allowOrigin(w, origin)
+
+			if exposeHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isPreflight tells a browser preflight from a plain OPTIONS request, which
+// goes to the route like any other method.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+func requestedHeaders(r *http.Request) []string {
+	var headers []string
+
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, header := range strings.Split(value, ",") {
+			header = strings.ToLower(strings.TrimSpace(header))
+			if header != "" {
+				headers = append(headers, header)
+			}
+		}
+	}
+
+	return headers
+}
@@ -0,0 +1,236 @@
+package cors
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  Policy
+		wantErr bool
+	}{
+		{name: "origin", policy: Policy{AllowedOrigins: []string{"http://localhost:3000"}}},
+		{name: "origin with credentials", policy: Policy{AllowedOrigins: []string{"https://example.com"}, AllowCredentials: true}},
+		{name: "any origin", policy: Policy{AllowedOrigins: []string{"*"}}},
+		{name: "any origin with credentials", policy: Policy{AllowedOrigins: []string{"*"}, AllowCredentials: true}, wantErr: true},
+		{name: "no origins", policy: Policy{}, wantErr: true},
+		{name: "origin without scheme", policy: Policy{AllowedOrigins: []string{"localhost:3000"}}, wantErr: true},
+		{name: "origin with path", policy: Policy{AllowedOrigins: []string{"http://localhost:3000/app"}}, wantErr: true},
+		{name: "origin with trailing slash", policy: Policy{AllowedOrigins: []string{"http://localhost:3000/"}}, wantErr: true},
+		{name: "ftp origin", policy: Policy{AllowedOrigins: []string{"ftp://example.com"}}, wantErr: true},
+		{name: "unknown method", policy: Policy{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"FETCH"}}, wantErr: true},
+		{name: "negative max age", policy: Policy{AllowedOrigins: []string{"*"}, MaxAge: -time.Second}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := tt.policy.Validate()
+		if tt.wantErr && !errors.Is(err, ErrInvalidPolicy) {
+			t.Errorf("%s: Validate = %v, want ErrInvalidPolicy", tt.name, err)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%s: Validate = %v, want nil", tt.name, err)
+		}
+	}
+}
+
+// serve runs req through the policy in front of a handler that answers 200
+// and reports whether the request got past the policy.
+func serve(p *Policy, req *http.Request) (*httptest.ResponseRecorder, bool) {
+	reached := false
+
+	handler := p.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec, reached
+}
+
+func TestPreflight(t *testing.T) {
+	policy := &Policy{
+		AllowedOrigins: []string{"http://localhost:3000"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		MaxAge:         10 * time.Minute,
+	}
+
+	tests := []struct {
+		name        string
+		origin      string
+		method      string
+		headers     string
+		wantStatus  int
+		wantAllowed bool
+	}{
+		{name: "allowed", origin: "http://localhost:3000", method: "POST", headers: "Authorization, Content-Type", wantStatus: http.StatusNoContent, wantAllowed: true},
+		{name: "origin case", origin: "http://LOCALHOST:3000", method: "GET", wantStatus: http.StatusNoContent, wantAllowed: true},
+		{name: "header case", origin: "http://localhost:3000", method: "GET", headers: "authorization", wantStatus: http.StatusNoContent, wantAllowed: true},
+		{name: "disallowed origin", origin: "http://evil.example", method: "GET", wantStatus: http.StatusForbidden},
+		{name: "disallowed method", origin: "http://localhost:3000", method: "OPTIONS", wantStatus: http.StatusForbidden},
+		{name: "disallowed header", origin: "http://localhost:3000", method: "GET", headers: "X-Debug", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/api/movies", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+
+			rec, reached := serve(policy, req)
+
+			if reached {
+				t.Fatal("preflight reached the route")
+			}
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if !strings.Contains(rec.Header().Get("Vary"), "Origin") {
+				t.Errorf("Vary = %q, want it to contain Origin", rec.Header().Get("Vary"))
+			}
+
+			allowOrigin := rec.Header().Get("Access-Control-Allow-Origin")
+			if !tt.wantAllowed {
+				for key := range rec.Header() {
+					if strings.HasPrefix(key, "Access-Control-") {
+						t.Errorf("rejected preflight has %s", key)
+					}
+				}
+				return
+			}
+
+			if allowOrigin != tt.origin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", allowOrigin, tt.origin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Methods"); got != strings.Join(DefaultMethods, ", ") {
+				t.Errorf("Access-Control-Allow-Methods = %q", got)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "Authorization, Content-Type" {
+				t.Errorf("Access-Control-Allow-Headers = %q", got)
+			}
+			if got := rec.Header().Get("Access-Control-Max-Age"); got != "600" {
+				t.Errorf("Access-Control-Max-Age = %q, want 600", got)
+			}
+		})
+	}
+}
+
+func TestPreflightAnyHeader(t *testing.T) {
+	policy := &Policy{AllowedOrigins: []string{"*"}, AllowedHeaders: []string{"*"}}
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/movies", nil)
+	req.Header.Set("Origin", "http://example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	req.Header.Set("Access-Control-Request-Headers", "X-Debug, X-Trace")
+
+	rec, _ := serve(policy, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want 204", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Headers"); got != "x-debug, x-trace" {
+		t.Errorf("Access-Control-Allow-Headers = %q, want the requested headers", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+}
+
+func TestActualRequest(t *testing.T) {
+	tests := []struct {
+		name            string
+		policy          Policy
+		method          string
+		origin          string
+		wantAllowOrigin string
+		wantCredentials bool
+		wantVary        bool
+	}{
+		{
+			name:            "allowed origin",
+			policy:          Policy{AllowedOrigins: []string{"http://localhost:3000"}, ExposedHeaders: []string{"X-Request-ID"}},
+			origin:          "http://localhost:3000",
+			wantAllowOrigin: "http://localhost:3000",
+			wantVary:        true,
+		},
+		{
+			name:     "other origin",
+			policy:   Policy{AllowedOrigins: []string{"http://localhost:3000"}},
+			origin:   "http://evil.example",
+			wantVary: true,
+		},
+		{
+			name:     "no origin",
+			policy:   Policy{AllowedOrigins: []string{"http://localhost:3000"}},
+			wantVary: true,
+		},
+		{
+			name:            "any origin",
+			policy:          Policy{AllowedOrigins: []string{"*"}},
+			origin:          "http://example.com",
+			wantAllowOrigin: "*",
+		},
+		{
+			name:            "credentials echo the origin",
+			policy:          Policy{AllowedOrigins: []string{"http://localhost:3000"}, AllowCredentials: true},
+			origin:          "http://localhost:3000",
+			wantAllowOrigin: "http://localhost:3000",
+			wantCredentials: true,
+			wantVary:        true,
+		},
+		{
+			name:            "plain options goes to the route",
+			policy:          Policy{AllowedOrigins: []string{"http://localhost:3000"}},
+			method:          http.MethodOptions,
+			origin:          "http://localhost:3000",
+			wantAllowOrigin: "http://localhost:3000",
+			wantVary:        true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			req := httptest.NewRequest(method, "/api/movies", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+
+			rec, reached := serve(&tt.policy, req)
+
+			if !reached {
+				t.Fatal("request did not reach the route")
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllowOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tt.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials set = %v, want %v", got, tt.wantCredentials)
+			}
+			if got := rec.Header().Get("Vary") == "Origin"; got != tt.wantVary {
+				t.Errorf("Vary = %q, want Origin: %v", rec.Header().Get("Vary"), tt.wantVary)
+			}
+
+			wantExpose := ""
+			if tt.wantAllowOrigin != "" {
+				wantExpose = strings.Join(tt.policy.ExposedHeaders, ", ")
+			}
+			if got := rec.Header().Get("Access-Control-Expose-Headers"); got != wantExpose {
+				t.Errorf("Access-Control-Expose-Headers = %q, want %q", got, wantExpose)
+			}
+		})
+	}
+}
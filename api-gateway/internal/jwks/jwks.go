@@ -0,0 +1,190 @@
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRefreshInterval bounds how often an unknown kid may trigger a refetch,
+// so tokens with made-up kids cannot be used to flood auth-service.
+const minRefreshInterval = 10 * time.Second
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type key struct {
+	algorithm string
+	public    crypto.PublicKey
+}
+
+// Cache holds the public keys published by auth-service and refetches them
+// periodically and whenever a token references a kid it has not seen yet.
+type Cache struct {
+	url    string
+	client *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]key
+	lastAttempt time.Time
+}
+
+func NewCache(url string) *Cache {
+	return &Cache{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+		keys:   make(map[string]key),
+	}
+}
+
+func (c *Cache) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := c.Refresh(ctx)
+			if err != nil {
+				slog.Error("JWKS refresh error", "error", err)
+			}
+		}
+	}
+}
+
+func (c *Cache) Refresh(ctx context.Context) error {
+	c.mu.Lock()
+	c.lastAttempt = time.Now()
+	c.mu.Unlock()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected JWKS status %d", response.StatusCode)
+	}
+
+	var body struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&body)
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]key, len(body.Keys))
+
+	for _, k := range body.Keys {
+		public, err := k.publicKey()
+		if err != nil {
+			slog.Warn("Skipping JWK", "kid", k.Kid, "error", err)
+			continue
+		}
+		keys[k.Kid] = key{algorithm: k.Alg, public: public}
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Keyfunc resolves the verification key for a token by its kid header.
+func (c *Cache) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+
+	k, ok := c.lookup(kid)
+	if !ok && c.startRefresh() {
+		err := c.Refresh(context.Background())
+		if err != nil {
+			slog.Error("JWKS refresh error", "error", err)
+		}
+		k, ok = c.lookup(kid)
+	}
+
+	if !ok || k.algorithm != t.Method.Alg() {
+		return nil, ErrUnknownKey
+	}
+
+	return k.public, nil
+}
+
+func (c *Cache) lookup(kid string) (key, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	k, ok := c.keys[kid]
+	return k, ok
+}
+
+func (c *Cache) startRefresh() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.lastAttempt) < minRefreshInterval {
+		return false
+	}
+
+	c.lastAttempt = time.Now()
+	return true
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch {
+	case k.Kty == "OKP" && k.Crv == "Ed25519" && k.Alg == "EdDSA":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	case k.Kty == "RSA" && k.Alg == "RS256":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, errors.New("invalid RSA modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	}
+
+	return nil, errors.New("unsupported key type")
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/uptrace/opentelemetry-go-extra/otelsql"
+)
+
+const (
+	defaultConnectTimeout = time.Minute
+
+	connectBaseDelay = 500 * time.Millisecond
+	connectMaxDelay  = 10 * time.Second
+)
+
+type Db struct {
+	*sql.DB
+}
+
+func NewConnectDb() (*Db, error) {
+	dbUrl := os.Getenv("DB_URL")
+	// Every query gets a span with its statement as a child of the request span.
+	db, err := otelsql.Open("postgres", dbUrl, otelsql.WithDBSystem("postgresql"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to DB: %w", err)
+	}
+	err = waitForDb(db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error pinging database: %w", err)
+	}
+	return &Db{db}, nil
+}
+
+// waitForDb pings until the database answers, doubling the delay between
+// attempts up to connectMaxDelay. A database that is still starting up does
+// not stop the service, DB_CONNECT_TIMEOUT bounds the wait (default 1m).
+func waitForDb(db *sql.DB) error {
+	timeout, err := time.ParseDuration(os.Getenv("DB_CONNECT_TIMEOUT"))
+	if err != nil {
+		timeout = defaultConnectTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	delay := connectBaseDelay
+
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return err
+		}
+
+		slog.Warn("Database is not ready, retrying", "attempt", attempt, "retry_in", delay.String(), "error", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+
+		delay = min(2*delay, connectMaxDelay)
+	}
+}
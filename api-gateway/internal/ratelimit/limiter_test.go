@@ -0,0 +1,45 @@
+package ratelimit
+
+import (
+	"api-gateway/middleware"
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCallerKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		withUser   bool
+		userID     uint
+		clientID   string
+		remoteAddr string
+		want       string
+	}{
+		{name: "user wins over client and address", withUser: true, userID: 42, clientID: "svc", remoteAddr: "10.0.0.1:5000", want: "user:42"},
+		{name: "client wins over address", clientID: "svc", remoteAddr: "10.0.0.1:5000", want: "client:svc"},
+		{name: "zero user is anonymous", withUser: true, userID: 0, clientID: "svc", remoteAddr: "10.0.0.1:5000", want: "client:svc"},
+		{name: "address without port", remoteAddr: "10.0.0.1", want: "ip:10.0.0.1"},
+		{name: "address", remoteAddr: "10.0.0.1:5000", want: "ip:10.0.0.1"},
+		{name: "ipv6 address", remoteAddr: "[::1]:5000", want: "ip:::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/movies", nil)
+			r.RemoteAddr = tt.remoteAddr
+
+			if tt.withUser {
+				r = r.WithContext(context.WithValue(r.Context(), middleware.UserIDKey, tt.userID))
+			}
+			if tt.clientID != "" {
+				r.Header.Set(middleware.ClientIDHeader, tt.clientID)
+			}
+
+			got := callerKey(r)
+			if got != tt.want {
+				t.Errorf("callerKey = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"api-gateway/internal/postgres"
+	"context"
+	"log/slog"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table, so all gateway
+// replicas share them. Each take locks the bucket row, which serialises
+// concurrent requests of one client but not of different clients.
+type PostgresStore struct {
+	Database *postgres.Db
+}
+
+func NewPostgresStore(db *postgres.Db) *PostgresStore {
+	return &PostgresStore{
+		Database: db,
+	}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	tx, err := s.Database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, ErrFailedTakeRateSlot
+	}
+
+	defer tx.Rollback()
+
+	// A new bucket is full; inserting it first gives the next select a row to
+	// lock even for the very first request of a client.
+	query, args, err := sq.
+		Insert("rate_limit_buckets").
+		Columns("key", "tokens", "updated_at", "expires_at").
+		Values(key, limit.capacity(), now, now).
+		Suffix("ON CONFLICT (key) DO NOTHING").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return Result{}, ErrFailedTakeRateSlot
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return Result{}, ErrFailedTakeRateSlot
+	}
+
+	query, args, err = sq.
+		Select("tokens", "updated_at").
+		From("rate_limit_buckets").
+		Where(sq.Eq{"key": key}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return Result{}, ErrFailedTakeRateSlot
+	}
+
+	var b bucket
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&b.tokens, &b.updatedAt)
+	if err != nil {
+		return Result{}, ErrFailedTakeRateSlot
+	}
+
+	result, fullAt := b.take(limit, now)
+
+	query, args, err = sq.
+		Update("rate_limit_buckets").
+		Set("tokens", b.tokens).
+		Set("updated_at", b.updatedAt).
+		Set("expires_at", fullAt).
+		Where(sq.Eq{"key": key}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return Result{}, ErrFailedTakeRateSlot
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return Result{}, ErrFailedTakeRateSlot
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return Result{}, ErrFailedTakeRateSlot
+	}
+
+	return result, nil
+}
+
+// Run deletes buckets that have refilled, a missing bucket is a full one.
+func (s *PostgresStore) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.sweep(ctx, time.Now())
+			if err != nil {
+				slog.Error("Rate limit bucket cleanup failed", "error", err)
+			}
+		}
+	}
+}
+
+func (s *PostgresStore) sweep(ctx context.Context, now time.Time) error {
+	query, args, err := sq.
+		Delete("rate_limit_buckets").
+		Where(sq.LtOrEq{"expires_at": now}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return err
+	}
+
+	_, err = s.Database.DB.ExecContext(ctx, query, args...)
+	return err
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var (
+	ErrInvalidLimit       = errors.New("invalid rate limit")
+	ErrFailedTakeRateSlot = errors.New("failed to take rate limit slot")
+)
+
+// Limit is a token bucket: it refills at Requests per Per and holds up to
+// Burst tokens. Without Burst the bucket holds Requests tokens.
+type Limit struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per"`
+	Burst    int           `yaml:"burst"`
+}
+
+func (l Limit) Validate() error {
+	if l.Requests <= 0 {
+		return fmt.Errorf("%w: requests must be positive", ErrInvalidLimit)
+	}
+
+	if l.Per <= 0 {
+		return fmt.Errorf("%w: per must be a positive duration", ErrInvalidLimit)
+	}
+
+	if l.Burst < 0 {
+		return fmt.Errorf("%w: burst cannot be negative", ErrInvalidLimit)
+	}
+
+	return nil
+}
+
+func (l Limit) capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.Requests)
+}
+
+// perSecond is the refill rate in tokens per second.
+func (l Limit) perSecond() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Policy is the RateLimit-Policy header value.
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d;burst=%d", l.Requests, int(math.Ceil(l.Per.Seconds())), int(l.capacity()))
+}
+
+// Result describes the bucket after a request took, or failed to take, a token.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. Take refills the bucket for key up to now and
+// takes one token from it if there is one.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// take is shared by the stores so they agree on the arithmetic. A missing
+// bucket is a full one. It returns the updated bucket and the time it will be
+// full again, after which the stores may forget it.
+func (b *bucket) take(limit Limit, now time.Time) (Result, time.Time) {
+	capacity := limit.capacity()
+	rate := limit.perSecond()
+
+	tokens := capacity
+	if !b.updatedAt.IsZero() {
+		elapsed := now.Sub(b.updatedAt).Seconds()
+		tokens = math.Min(capacity, b.tokens+math.Max(0, elapsed)*rate)
+	}
+
+	result := Result{Limit: int(capacity)}
+
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+
+	b.tokens = tokens
+	b.updatedAt = now
+
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = seconds((capacity - tokens) / rate)
+
+	return result, now.Add(result.Reset)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketTake(t *testing.T) {
+	// 60 per minute refills one token a second, the bucket holds 3.
+	limit := Limit{Requests: 60, Per: time.Minute, Burst: 3}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		at            time.Duration
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+	}{
+		{name: "new bucket is full", at: 0, wantAllowed: true, wantRemaining: 2},
+		{name: "burst", at: 0, wantAllowed: true, wantRemaining: 1},
+		{name: "burst drains", at: 0, wantAllowed: true, wantRemaining: 0},
+		{name: "empty", at: 0, wantAllowed: false, wantRemaining: 0, wantRetry: time.Second},
+		{name: "partly refilled", at: 500 * time.Millisecond, wantAllowed: false, wantRemaining: 0, wantRetry: 500 * time.Millisecond},
+		{name: "one token refilled", at: time.Second, wantAllowed: true, wantRemaining: 0},
+		{name: "refill is capped at burst", at: time.Hour, wantAllowed: true, wantRemaining: 2},
+		{name: "clock going back adds nothing", at: time.Hour - time.Minute, wantAllowed: true, wantRemaining: 1},
+	}
+
+	var b bucket
+
+	for _, tt := range tests {
+		result, _ := b.take(limit, start.Add(tt.at))
+
+		if result.Allowed != tt.wantAllowed {
+			t.Fatalf("%s: Allowed = %v, want %v", tt.name, result.Allowed, tt.wantAllowed)
+		}
+		if result.Remaining != tt.wantRemaining {
+			t.Errorf("%s: Remaining = %d, want %d", tt.name, result.Remaining, tt.wantRemaining)
+		}
+		if result.RetryAfter != tt.wantRetry {
+			t.Errorf("%s: RetryAfter = %v, want %v", tt.name, result.RetryAfter, tt.wantRetry)
+		}
+		if result.Limit != 3 {
+			t.Errorf("%s: Limit = %d, want 3", tt.name, result.Limit)
+		}
+	}
+}
+
+func TestBucketTakeWithoutBurst(t *testing.T) {
+	limit := Limit{Requests: 2, Per: time.Second}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var b bucket
+
+	for i := range 2 {
+		result, _ := b.take(limit, now)
+		if !result.Allowed {
+			t.Fatalf("request %d was limited, the bucket holds Requests tokens", i+1)
+		}
+	}
+
+	result, fullAt := b.take(limit, now)
+	if result.Allowed {
+		t.Fatal("third request in the same instant was allowed")
+	}
+
+	if want := now.Add(time.Second); !fullAt.Equal(want) {
+		t.Errorf("full at %v, want %v", fullAt, want)
+	}
+	if result.Reset != time.Second {
+		t.Errorf("Reset = %v, want 1s", result.Reset)
+	}
+}
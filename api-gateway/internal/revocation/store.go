@@ -0,0 +1,246 @@
+package revocation
+
+import (
+	"api-gateway/internal/postgres"
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+const maxCacheEntries = 10000
+
+var ErrFailedCheckRevocation = errors.New("failed to check token revocation")
+
+type cacheEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+// Store answers revocation lookups from the auth database and keeps the
+// results in memory for ttl, so a revoked token is rejected at most ttl after
+// it was revoked without querying the database on every request.
+type Store struct {
+	Database *postgres.Db
+	ttl      time.Duration
+
+	mu       sync.Mutex
+	tokens   map[string]cacheEntry[bool]
+	sessions map[string]cacheEntry[bool]
+	clients  map[string]cacheEntry[bool]
+	users    map[uint]cacheEntry[sql.NullTime]
+}
+
+func NewStore(db *postgres.Db, ttl time.Duration) *Store {
+	return &Store{
+		Database: db,
+		ttl:      ttl,
+		tokens:   make(map[string]cacheEntry[bool]),
+		sessions: make(map[string]cacheEntry[bool]),
+		clients:  make(map[string]cacheEntry[bool]),
+		users:    make(map[uint]cacheEntry[sql.NullTime]),
+	}
+}
+
+// IsRevoked checks the token itself, its session (tokens issued before
+// sessions existed have none), the OAuth client it was issued to, if any, and
+// the user-wide revocation time. Client credentials tokens have no user.
+func (s *Store) IsRevoked(ctx context.Context, jti string, userID uint, sessionID string, clientID string, issuedAt time.Time) (bool, error) {
+	revoked, err := s.isTokenRevoked(ctx, jti)
+	if err != nil || revoked {
+		return revoked, err
+	}
+
+	if sessionID != "" {
+		revoked, err = s.isSessionRevoked(ctx, sessionID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+	}
+
+	if clientID != "" {
+		revoked, err = s.isClientRevoked(ctx, clientID)
+		if err != nil || revoked {
+			return revoked, err
+		}
+
+		if userID == 0 {
+			return false, nil
+		}
+	}
+
+	revokedAt, err := s.userTokensRevokedAt(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return revokedAt.Valid && issuedAt.Unix() <= revokedAt.Time.Unix(), nil
+}
+
+func (s *Store) isTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	s.mu.Lock()
+	entry, ok := s.tokens[jti]
+	s.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.value, nil
+	}
+
+	query, args, err := sq.
+		Select("1").
+		From("revoked_tokens").
+		Where(sq.Eq{"jti": jti}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, ErrFailedCheckRevocation
+	}
+
+	var found int
+
+	revoked := true
+
+	err = s.Database.DB.QueryRowContext(ctx, query, args...).Scan(&found)
+	if err == sql.ErrNoRows {
+		revoked = false
+	} else if err != nil {
+		return false, ErrFailedCheckRevocation
+	}
+
+	s.mu.Lock()
+	if len(s.tokens) >= maxCacheEntries {
+		sweep(s.tokens)
+	}
+	s.tokens[jti] = cacheEntry[bool]{value: revoked, expiresAt: time.Now().Add(s.ttl)}
+	s.mu.Unlock()
+
+	return revoked, nil
+}
+
+func (s *Store) isSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	s.mu.Lock()
+	entry, ok := s.sessions[sessionID]
+	s.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.value, nil
+	}
+
+	query, args, err := sq.
+		Select("revoked_at").
+		From("sessions").
+		Where(sq.Eq{"id": sessionID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, ErrFailedCheckRevocation
+	}
+
+	var revokedAt sql.NullTime
+
+	err = s.Database.DB.QueryRowContext(ctx, query, args...).Scan(&revokedAt)
+	if err == sql.ErrNoRows {
+		// A missing session was deleted together with its user.
+		revokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	} else if err != nil {
+		return false, ErrFailedCheckRevocation
+	}
+
+	s.mu.Lock()
+	if len(s.sessions) >= maxCacheEntries {
+		sweep(s.sessions)
+	}
+	s.sessions[sessionID] = cacheEntry[bool]{value: revokedAt.Valid, expiresAt: time.Now().Add(s.ttl)}
+	s.mu.Unlock()
+
+	return revokedAt.Valid, nil
+}
+
+func (s *Store) isClientRevoked(ctx context.Context, clientID string) (bool, error) {
+	s.mu.Lock()
+	entry, ok := s.clients[clientID]
+	s.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.value, nil
+	}
+
+	query, args, err := sq.
+		Select("revoked_at").
+		From("oauth_clients").
+		Where(sq.Eq{"id": clientID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, ErrFailedCheckRevocation
+	}
+
+	var revokedAt sql.NullTime
+
+	err = s.Database.DB.QueryRowContext(ctx, query, args...).Scan(&revokedAt)
+	if err == sql.ErrNoRows {
+		revokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	} else if err != nil {
+		return false, ErrFailedCheckRevocation
+	}
+
+	s.mu.Lock()
+	if len(s.clients) >= maxCacheEntries {
+		sweep(s.clients)
+	}
+	s.clients[clientID] = cacheEntry[bool]{value: revokedAt.Valid, expiresAt: time.Now().Add(s.ttl)}
+	s.mu.Unlock()
+
+	return revokedAt.Valid, nil
+}
+
+func (s *Store) userTokensRevokedAt(ctx context.Context, userID uint) (sql.NullTime, error) {
+	s.mu.Lock()
+	entry, ok := s.users[userID]
+	s.mu.Unlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.value, nil
+	}
+
+	query, args, err := sq.
+		Select("tokens_revoked_at").
+		From("users").
+		Where(sq.Eq{"id": userID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return sql.NullTime{}, ErrFailedCheckRevocation
+	}
+
+	var revokedAt sql.NullTime
+
+	err = s.Database.DB.QueryRowContext(ctx, query, args...).Scan(&revokedAt)
+	if err == sql.ErrNoRows {
+		// The user no longer exists, so every token it was issued is revoked.
+		revokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	} else if err != nil {
+		return sql.NullTime{}, ErrFailedCheckRevocation
+	}
+
+	s.mu.Lock()
+	if len(s.users) >= maxCacheEntries {
+		sweep(s.users)
+	}
+	s.users[userID] = cacheEntry[sql.NullTime]{value: revokedAt, expiresAt: time.Now().Add(s.ttl)}
+	s.mu.Unlock()
+
+	return revokedAt, nil
+}
+
+func sweep[K comparable, T any](entries map[K]cacheEntry[T]) {
+	now := time.Now()
+	for k, entry := range entries {
+		if now.After(entry.expiresAt) {
+			delete(entries, k)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package routes
+
+import (
+	"api-gateway/internal/cors"
+	"api-gateway/internal/ratelimit"
+	"api-gateway/internal/upstream"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	maxRetries = 5
+
+	// DefaultMaxBodySize applies to routes without max_body_size when the
+	// table does not set its own default.
+	DefaultMaxBodySize ByteSize = 1 << 20
+)
+
+var ErrInvalidConfig = errors.New("invalid route config")
+
+// DefaultAuthRateLimit applies when the table sets no auth_rate_limit.
+var DefaultAuthRateLimit = ratelimit.Limit{Requests: 600, Per: time.Minute, Burst: 120}
+
+var knownMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// Config is the route table of the gateway. It is read from YAML, so JSON
+// files work as well. CircuitBreaker applies to each upstream separately.
+// Without CORS the gateway sends no CORS headers and browsers only allow
+// same-origin calls. MaxBodySize is the body limit of routes without their own.
+// AuthRateLimit limits every client address on protected routes before the
+// token or API key is checked, so failed authentication is limited as well.
+type Config struct {
+	Upstreams      map[string]string         `yaml:"upstreams"`
+	CircuitBreaker *upstream.BreakerSettings `yaml:"circuit_breaker"`
+	CORS           *cors.Policy              `yaml:"cors"`
+	MaxBodySize    ByteSize                  `yaml:"max_body_size"`
+	AuthRateLimit  *ratelimit.Limit          `yaml:"auth_rate_limit"`
+	Routes         []Route                   `yaml:"routes"`
+}
+
+func (c *Config) authRateLimit() ratelimit.Limit {
+	if c.AuthRateLimit != nil {
+		return *c.AuthRateLimit
+	}
+	return DefaultAuthRateLimit
+}
+
+// Route sends requests matching Path to an upstream. Methods maps every
+// accepted method to the permission it requires, an empty permission only
+// requires a valid token or API key. Public routes skip authentication.
+// RateLimit, if set, limits every caller of the route separately. Timeout
+// bounds the upstream call including retries, Retries applies to idempotent
+// methods only. Larger request bodies than MaxBodySize are rejected with 413.
+type Route struct {
+	Path        string            `yaml:"path"`
+	Upstream    string            `yaml:"upstream"`
+	StripPrefix string            `yaml:"strip_prefix"`
+	Public      bool              `yaml:"public"`
+	Methods     map[string]string `yaml:"methods"`
+	Roles       []string          `yaml:"roles"`
+	RateLimit   *ratelimit.Limit  `yaml:"rate_limit"`
+	Timeout     time.Duration     `yaml:"timeout"`
+	Retries     *int              `yaml:"retries"`
+	MaxBodySize ByteSize          `yaml:"max_body_size"`
+}
+
+func (r *Route) timeout() time.Duration {
+	if r.Timeout > 0 {
+		return r.Timeout
+	}
+	return upstream.DefaultTimeout
+}
+
+func (r *Route) retries() int {
+	if r.Retries != nil {
+		return *r.Retries
+	}
+	return upstream.DefaultRetries
+}
+
+func (r *Route) maxBodySize(fallback ByteSize) int64 {
+	switch {
+	case r.MaxBodySize > 0:
+		return int64(r.MaxBodySize)
+	case fallback > 0:
+		return int64(fallback)
+	default:
+		return int64(DefaultMaxBodySize)
+	}
+}
+
+// ByteSize is a size in bytes. The file may give it as a number or with a KB,
+// MB or GB suffix, in powers of 1024: 512KB, 10MB.
+type ByteSize int64
+
+var byteSizeUnits = []struct {
+	suffix string
+	size   ByteSize
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+func (s *ByteSize) UnmarshalYAML(value *yaml.Node) error {
+	var text string
+
+	err := value.Decode(&text)
+	if err != nil {
+		return err
+	}
+
+	size, err := parseByteSize(text)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", value.Line, err)
+	}
+
+	*s = size
+
+	return nil
+}
+
+func parseByteSize(text string) (ByteSize, error) {
+	number := strings.ToUpper(strings.TrimSpace(text))
+	unit := ByteSize(1)
+
+	for _, u := range byteSizeUnits {
+		if strings.HasSuffix(number, u.suffix) {
+			number = strings.TrimSpace(strings.TrimSuffix(number, u.suffix))
+			unit = u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/int64(unit) {
+		return 0, fmt.Errorf("invalid size %q", text)
+	}
+
+	return ByteSize(n) * unit, nil
+}
+
+// LoadConfig reads and validates the route table at path.
+func LoadConfig(path string) (*Config, []byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var cfg Config
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	err = decoder.Decode(&cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &cfg, data, nil
+}
+
+// Validate reports the first problem in the table, naming the route so the
+// operator can find it.
+func (c *Config) Validate() error {
+	if len(c.Routes) == 0 {
+		return fmt.Errorf("%w: no routes", ErrInvalidConfig)
+	}
+
+	for name, address := range c.Upstreams {
+		_, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return fmt.Errorf("%w: upstream %q: address must be host:port", ErrInvalidConfig, name)
+		}
+	}
+
+	if c.CircuitBreaker != nil {
+		err := c.CircuitBreaker.Validate()
+		if err != nil {
+			return fmt.Errorf("%w: circuit_breaker: %v", ErrInvalidConfig, err)
+		}
+	}
+
+	if c.CORS != nil {
+		err := c.CORS.Validate()
+		if err != nil {
+			return fmt.Errorf("%w: cors: %v", ErrInvalidConfig, err)
+		}
+	}
+
+	if c.AuthRateLimit != nil {
+		err := c.AuthRateLimit.Validate()
+		if err != nil {
+			return fmt.Errorf("%w: auth_rate_limit: %v", ErrInvalidConfig, err)
+		}
+	}
+
+	seen := make(map[string]bool)
+
+	for i, route := range c.Routes {
+		where := fmt.Sprintf("route %d (%s)", i+1, route.Path)
+
+		if !strings.HasPrefix(route.Path, "/") {
+			return fmt.Errorf("%w: %s: path must start with /", ErrInvalidConfig, where)
+		}
+
+		if seen[route.Path] {
+			return fmt.Errorf("%w: %s: duplicate path", ErrInvalidConfig, where)
+		}
+		seen[route.Path] = true
+
+		if _, ok := c.Upstreams[route.Upstream]; !ok {
+			return fmt.Errorf("%w: %s: unknown upstream %q", ErrInvalidConfig, where, route.Upstream)
+		}
+
+		if !strings.HasPrefix(route.Path, route.StripPrefix) {
+			return fmt.Errorf("%w: %s: strip_prefix %q is not a prefix of the path", ErrInvalidConfig, where, route.StripPrefix)
+		}
+
+		if !route.Public && len(route.Methods) == 0 {
+			return fmt.Errorf("%w: %s: protected route needs methods", ErrInvalidConfig, where)
+		}
+
+		if route.Public && len(route.Roles) > 0 {
+			return fmt.Errorf("%w: %s: public route cannot require roles", ErrInvalidConfig, where)
+		}
+
+		for method, permission := range route.Methods {
+			if !knownMethods[method] {
+				return fmt.Errorf("%w: %s: unknown method %q", ErrInvalidConfig, where, method)
+			}
+
+			if route.Public && permission != "" {
+				return fmt.Errorf("%w: %s: public route cannot require permission %q", ErrInvalidConfig, where, permission)
+			}
+		}
+
+		if route.RateLimit != nil {
+			err := route.RateLimit.Validate()
+			if err != nil {
+				return fmt.Errorf("%w: %s: %v", ErrInvalidConfig, where, err)
+			}
+		}
+
+		if route.Timeout < 0 {
+			return fmt.Errorf("%w: %s: timeout cannot be negative", ErrInvalidConfig, where)
+		}
+
+		if route.Retries != nil && (*route.Retries < 0 || *route.Retries > maxRetries) {
+			return fmt.Errorf("%w: %s: retries must be between 0 and %d", ErrInvalidConfig, where, maxRetries)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package routes
+
+import (
+	"api-gateway/internal/ratelimit"
+	"api-gateway/internal/upstream"
+	"errors"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func validConfig() *Config {
+	return &Config{
+		Upstreams: map[string]string{"movies": "movies-service:8080"},
+		Routes: []Route{
+			{
+				Path:        "/api/movies",
+				Upstream:    "movies",
+				StripPrefix: "/api",
+				Methods:     map[string]string{"GET": "movies:read", "POST": "movies:write"},
+			},
+			{
+				Path:     "/api/movies/search",
+				Upstream: "movies",
+				Public:   true,
+				Methods:  map[string]string{"GET": ""},
+			},
+		},
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	negative := -1
+	tooMany := maxRetries + 1
+
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(c *Config) {}},
+		{name: "no routes", modify: func(c *Config) { c.Routes = nil }, wantErr: true},
+		{name: "upstream without port", modify: func(c *Config) { c.Upstreams["movies"] = "movies-service" }, wantErr: true},
+		{name: "invalid auth rate limit", modify: func(c *Config) { c.AuthRateLimit = &ratelimit.Limit{Per: time.Second} }, wantErr: true},
+		{name: "negative breaker failures", modify: func(c *Config) { c.CircuitBreaker = &upstream.BreakerSettings{Failures: -1} }, wantErr: true},
+		{name: "relative path", modify: func(c *Config) { c.Routes[0].Path = "api/movies" }, wantErr: true},
+		{name: "duplicate path", modify: func(c *Config) { c.Routes[1].Path = c.Routes[0].Path }, wantErr: true},
+		{name: "unknown upstream", modify: func(c *Config) { c.Routes[0].Upstream = "actors" }, wantErr: true},
+		{name: "strip prefix outside path", modify: func(c *Config) { c.Routes[0].StripPrefix = "/v1" }, wantErr: true},
+		{name: "protected route without methods", modify: func(c *Config) { c.Routes[0].Methods = nil }, wantErr: true},
+		{name: "public route without methods", modify: func(c *Config) { c.Routes[1].Methods = nil }},
+		{name: "public route with roles", modify: func(c *Config) { c.Routes[1].Roles = []string{"admin"} }, wantErr: true},
+		{name: "public route with permission", modify: func(c *Config) { c.Routes[1].Methods["GET"] = "movies:read" }, wantErr: true},
+		{name: "unknown method", modify: func(c *Config) { c.Routes[0].Methods["get"] = "" }, wantErr: true},
+		{name: "rate limit", modify: func(c *Config) { c.Routes[0].RateLimit = &ratelimit.Limit{Requests: 10, Per: time.Second} }},
+		{name: "invalid rate limit", modify: func(c *Config) { c.Routes[0].RateLimit = &ratelimit.Limit{Requests: 10} }, wantErr: true},
+		{name: "negative timeout", modify: func(c *Config) { c.Routes[0].Timeout = -time.Second }, wantErr: true},
+		{name: "negative retries", modify: func(c *Config) { c.Routes[0].Retries = &negative }, wantErr: true},
+		{name: "too many retries", modify: func(c *Config) { c.Routes[0].Retries = &tooMany }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if tt.wantErr && !errors.Is(err, ErrInvalidConfig) {
+				t.Fatalf("Validate = %v, want ErrInvalidConfig", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("Validate = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigShipped(t *testing.T) {
+	_, _, err := LoadConfig("../../config/routes.yaml")
+	if err != nil {
+		t.Fatalf("config/routes.yaml does not load: %v", err)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    ByteSize
+		wantErr bool
+	}{
+		{text: "1024", want: 1024},
+		{text: "512B", want: 512},
+		{text: "512KB", want: 512 << 10},
+		{text: "10MB", want: 10 << 20},
+		{text: "2GB", want: 2 << 30},
+		{text: " 10 mb ", want: 10 << 20},
+		{text: "1kb", want: 1 << 10},
+		{text: "", wantErr: true},
+		{text: "MB", wantErr: true},
+		{text: "0", wantErr: true},
+		{text: "-1KB", wantErr: true},
+		{text: "1.5MB", wantErr: true},
+		{text: "10TB", wantErr: true},
+		{text: "9223372036854775807GB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseByteSize(tt.text)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseByteSize(%q) = %d, want an error", tt.text, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseByteSize(%q): %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseByteSize(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestByteSizeUnmarshalYAML(t *testing.T) {
+	var cfg struct {
+		Number ByteSize `yaml:"number"`
+		Suffix ByteSize `yaml:"suffix"`
+	}
+
+	err := yaml.Unmarshal([]byte("number: 2048\nsuffix: 5MB\n"), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Number != 2048 || cfg.Suffix != 5<<20 {
+		t.Errorf("got %d and %d, want 2048 and %d", cfg.Number, cfg.Suffix, 5<<20)
+	}
+
+	err = yaml.Unmarshal([]byte("number: lots\n"), &cfg)
+	if err == nil {
+		t.Error("Unmarshal accepted an invalid size")
+	}
+}
+
+func TestRouteMaxBodySize(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    ByteSize
+		fallback ByteSize
+		want     int64
+	}{
+		{name: "route limit", route: 10 << 20, fallback: 2 << 20, want: 10 << 20},
+		{name: "table default", fallback: 2 << 20, want: 2 << 20},
+		{name: "built-in default", want: int64(DefaultMaxBodySize)},
+	}
+
+	for _, tt := range tests {
+		route := Route{MaxBodySize: tt.route}
+		if got := route.maxBodySize(tt.fallback); got != tt.want {
+			t.Errorf("%s: maxBodySize = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package routes
+
+import (
+	"api-gateway/internal/ratelimit"
+	"api-gateway/internal/upstream"
+	"api-gateway/middleware"
+	"api-gateway/pkg/metrics"
+	"api-gateway/pkg/res"
+	"api-gateway/pkg/tracing"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// internalPaths are served by every service for monitoring only. The gateway
+// never proxies them, whatever the route table says.
+var internalPaths = map[string]bool{
+	"/metrics": true,
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// proxyToService forwards to an upstream through transport. Every failure
+// to get an answer from the upstream is reported in the usual JSON envelope.
+func proxyToService(name string, target string, prefix string, transport http.RoundTripper, timeout time.Duration) http.Handler {
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{
+		Scheme: "http",
+		Host:   target,
+	})
+
+	proxy.Transport = transport
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		writeProxyError(w, r, name, err)
+	}
+
+	return withTimeout(timeout, http.StripPrefix(prefix, hideInternal(proxy)))
+}
+
+func hideInternal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if internalPaths[r.URL.Path] {
+			res.ErrResJson(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeProxyError(w http.ResponseWriter, r *http.Request, name string, err error) {
+	var (
+		openErr     *upstream.OpenError
+		maxBytesErr *http.MaxBytesError
+	)
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		res.ErrResJson(w, "request body too large", http.StatusRequestEntityTooLarge)
+	case errors.As(err, &openErr):
+		metrics.UpstreamErrors.WithLabelValues(name, "circuit_open").Inc()
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(openErr.RetryAfter.Seconds()))))
+		res.ErrResJson(w, "service "+name+" is unavailable", http.StatusServiceUnavailable)
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(r.Context().Err(), context.DeadlineExceeded):
+		metrics.UpstreamErrors.WithLabelValues(name, "timeout").Inc()
+		res.ErrResJson(w, "service "+name+" timed out", http.StatusGatewayTimeout)
+	default:
+		if !errors.Is(err, context.Canceled) {
+			metrics.UpstreamErrors.WithLabelValues(name, "unavailable").Inc()
+			slog.ErrorContext(r.Context(), "Proxy error", "upstream", name, "method", r.Method, "path", r.URL.Path, "error", err)
+		}
+		res.ErrResJson(w, "service "+name+" is unavailable", http.StatusBadGateway)
+	}
+}
+
+// limitBody answers 413 to requests declaring a body larger than limit. A body
+// sent without Content-Length is cut off at limit and the proxy then fails
+// with *http.MaxBytesError.
+func limitBody(limit int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			res.ErrResJson(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func withTimeout(timeout time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Router serves the current route table. A reload builds a complete new mux
+// and swaps it in at once, so requests see either the old or the new table,
+// never a mix, and a broken file leaves the old table in place.
+type Router struct {
+	Auth    *middleware.AuthMiddleware
+	Limiter *ratelimit.Limiter
+	path    string
+
+	mu        sync.Mutex
+	table     atomic.Pointer[table]
+	data      []byte
+	breakers  map[string]*upstream.Breaker
+	upstreams map[string]string
+}
+
+// NewRouter loads the table at path. Unlike a reload, a problem here is fatal
+// for the caller: the gateway must not start without routes.
+func NewRouter(path string, auth *middleware.AuthMiddleware, limiter *ratelimit.Limiter) (*Router, error) {
+	r := &Router{
+		Auth:     auth,
+		Limiter:  limiter,
+		path:     path,
+		breakers: make(map[string]*upstream.Breaker),
+	}
+
+	_, err := r.Reload()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.table.Load().handler.ServeHTTP(w, req)
+}
+
+// Handler reports the route a request would take, like http.ServeMux.Handler.
+func (r *Router) Handler(req *http.Request) (http.Handler, string) {
+	return r.table.Load().mux.Handler(req)
+}
+
+// Upstreams returns the services of the current table by name and address.
+func (r *Router) Upstreams() map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return maps.Clone(r.upstreams)
+}
+
+// Reload rereads the file and reports whether its content changed.
+func (r *Router) Reload() (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, data, err := LoadConfig(r.path)
+	if err != nil {
+		return false, err
+	}
+
+	if r.table.Load() != nil && bytes.Equal(data, r.data) {
+		return false, nil
+	}
+
+	t, err := r.build(cfg)
+	if err != nil {
+		return false, err
+	}
+
+	r.table.Store(t)
+	r.data = data
+	r.upstreams = cfg.Upstreams
+
+	return true, nil
+}
+
+// table is a built route table. handler is mux behind the CORS policy, if the
+// table has one.
+type table struct {
+	mux     *http.ServeMux
+	handler http.Handler
+}
+
+func (r *Router) build(cfg *Config) (t *table, err error) {
+	// ServeMux panics on patterns it cannot register.
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("%w: %v", ErrInvalidConfig, p)
+		}
+	}()
+
+	mux := http.NewServeMux()
+
+	// Breakers belong to upstream addresses and outlive reloads, so a new
+	// table does not forget a failing upstream.
+	breakers := make(map[string]*upstream.Breaker)
+
+	for _, address := range cfg.Upstreams {
+		breaker, ok := r.breakers[address]
+		if ok {
+			breaker.Configure(cfg.CircuitBreaker)
+		} else {
+			breaker = upstream.NewBreaker(cfg.CircuitBreaker)
+		}
+		breakers[address] = breaker
+	}
+
+	base := tracing.Transport(http.DefaultTransport)
+	authLimit := cfg.authRateLimit()
+
+	for _, route := range cfg.Routes {
+		transport := &upstream.Transport{
+			Base:    base,
+			Breaker: breakers[cfg.Upstreams[route.Upstream]],
+			Retries: route.retries(),
+		}
+
+		var handler http.Handler = proxyToService(route.Upstream, cfg.Upstreams[route.Upstream], route.StripPrefix, transport, route.timeout())
+
+		if route.RateLimit != nil {
+			handler = r.Limiter.Handler(route.Path, *route.RateLimit, handler)
+		}
+
+		switch {
+		case route.Public && len(route.Methods) > 0:
+			handler = allowMethods(route.Methods, handler)
+		case !route.Public:
+			if len(route.Roles) > 0 {
+				handler = middleware.RequireRoles(route.Roles, handler)
+			}
+			handler = r.Auth.RequirePermissions(route.Methods, handler)
+			// One bucket per address for all protected routes.
+			handler = r.Limiter.ClientHandler("authenticate", authLimit, handler)
+		}
+
+		handler = limitBody(route.maxBodySize(cfg.MaxBodySize), handler)
+
+		mux.Handle(route.Path, handler)
+	}
+
+	r.breakers = breakers
+
+	t = &table{mux: mux, handler: mux}
+
+	if cfg.CORS != nil {
+		t.handler = cfg.CORS.Handler(mux)
+	}
+
+	return t, nil
+}
+
+// Watch reloads the table on SIGHUP and whenever the file content changes,
+// checking every interval. Polling also catches editors and config mounts
+// that replace the file instead of writing to it.
+func (r *Router) Watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.reloadAndLog("SIGHUP")
+		case <-ticker.C:
+			r.reloadAndLog("file change")
+		}
+	}
+}
+
+func (r *Router) reloadAndLog(reason string) {
+	changed, err := r.Reload()
+	if err != nil {
+		slog.Error("Route table reload failed, keeping the previous table", "reason", reason, "error", err)
+		return
+	}
+
+	if changed {
+		slog.Info("Route table reloaded", "path", r.path, "reason", reason)
+	}
+}
+
+func allowMethods(methods map[string]string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := methods[r.Method]; !ok {
+			res.ErrResJson(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,89 @@
+package routes
+
+import (
+	"api-gateway/internal/ratelimit"
+	"api-gateway/middleware"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const floodTable = `
+upstreams:
+  movies: 127.0.0.1:1
+auth_rate_limit:
+  requests: 3
+  per: 1m
+routes:
+  - path: /api/movies
+    upstream: movies
+    strip_prefix: /api
+    methods:
+      GET: movies:read
+  - path: /api/actors
+    upstream: movies
+    strip_prefix: /api
+    methods:
+      GET: actors:read
+`
+
+func newTestRouter(t *testing.T, table string) *Router {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+
+	err := os.WriteFile(path, []byte(table), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests without valid credentials never reach the key cache, the
+	// revocation store or the API key store.
+	auth := middleware.NewAuthMiddleware(nil, nil, nil)
+
+	router, err := NewRouter(path, auth, ratelimit.NewLimiter(ratelimit.NewMemoryStore()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return router
+}
+
+func TestUnauthenticatedFloodIsLimited(t *testing.T) {
+	router := newTestRouter(t, floodTable)
+
+	tests := []struct {
+		name          string
+		path          string
+		remoteAddr    string
+		authorization string
+		want          int
+	}{
+		{name: "missing credentials", path: "/api/movies", remoteAddr: "10.0.0.1:5000", want: http.StatusUnauthorized},
+		{name: "malformed token", path: "/api/movies", remoteAddr: "10.0.0.1:5001", authorization: "Bearer not-a-jwt", want: http.StatusUnauthorized},
+		{name: "other route, same bucket", path: "/api/actors", remoteAddr: "10.0.0.1:5002", want: http.StatusUnauthorized},
+		{name: "limit reached", path: "/api/movies", remoteAddr: "10.0.0.1:5003", want: http.StatusTooManyRequests},
+		{name: "api keys are not looked up", path: "/api/movies", remoteAddr: "10.0.0.1:5004", authorization: "ApiKey made-up", want: http.StatusTooManyRequests},
+		{name: "other address", path: "/api/movies", remoteAddr: "10.0.0.2:5000", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		req.RemoteAddr = tt.remoteAddr
+		if tt.authorization != "" {
+			req.Header.Set("Authorization", tt.authorization)
+		}
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Fatalf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if tt.want == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+			t.Errorf("%s: 429 without Retry-After", tt.name)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBreakerFailures = 5
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+var (
+	ErrCircuitOpen     = errors.New("circuit open")
+	ErrInvalidSettings = errors.New("invalid upstream settings")
+)
+
+// BreakerSettings configures the breaker of every upstream: it opens after
+// Failures consecutive failures and lets a probe through after Cooldown.
+type BreakerSettings struct {
+	Failures int           `yaml:"failures"`
+	Cooldown time.Duration `yaml:"cooldown"`
+}
+
+func (s *BreakerSettings) Validate() error {
+	if s.Failures < 0 {
+		return fmt.Errorf("%w: failures cannot be negative", ErrInvalidSettings)
+	}
+
+	if s.Cooldown < 0 {
+		return fmt.Errorf("%w: cooldown cannot be negative", ErrInvalidSettings)
+	}
+
+	return nil
+}
+
+func (s *BreakerSettings) withDefaults() BreakerSettings {
+	settings := BreakerSettings{
+		Failures: DefaultBreakerFailures,
+		Cooldown: DefaultBreakerCooldown,
+	}
+
+	if s != nil && s.Failures > 0 {
+		settings.Failures = s.Failures
+	}
+
+	if s != nil && s.Cooldown > 0 {
+		settings.Cooldown = s.Cooldown
+	}
+
+	return settings
+}
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// Breaker stops calls to an upstream that keeps failing, so clients get an
+// error at once instead of waiting on timeouts. When open it lets a single
+// probe through after the cooldown: success closes it, failure opens it again.
+type Breaker struct {
+	mu       sync.Mutex
+	settings BreakerSettings
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func NewBreaker(settings *BreakerSettings) *Breaker {
+	return &Breaker{
+		settings: settings.withDefaults(),
+	}
+}
+
+// Configure applies new settings and keeps the current state, so reloading
+// the route table does not close an open breaker.
+func (b *Breaker) Configure(settings *BreakerSettings) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.settings = settings.withDefaults()
+}
+
+// OpenError rejects a call while the breaker is open. RetryAfter is the time
+// left until the next probe.
+type OpenError struct {
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return ErrCircuitOpen.Error()
+}
+
+func (e *OpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+// Allow reports whether a call may go ahead, every allowed call must end with
+// Record or Abandon.
+func (b *Breaker) Allow(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case stateOpen:
+		wait := b.openedAt.Add(b.settings.Cooldown).Sub(now)
+		if wait > 0 {
+			return &OpenError{RetryAfter: wait}
+		}
+		b.state = stateHalfOpen
+		return nil
+	case stateHalfOpen:
+		// The probe is still running.
+		return &OpenError{RetryAfter: time.Second}
+	default:
+		return nil
+	}
+}
+
+// Record reports the outcome of an allowed call.
+func (b *Breaker) Record(success bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.state = stateClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+
+	if b.state == stateHalfOpen || b.failures >= b.settings.Failures {
+		b.state = stateOpen
+		b.openedAt = now
+	}
+}
+
+// Abandon is called instead of Record when the client gave up before the
+// upstream answered. Such a call says nothing about the upstream, but a
+// probe must not leave the breaker waiting for it forever.
+func (b *Breaker) Abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == stateHalfOpen {
+		b.state = stateOpen
+	}
+}
@@ -0,0 +1,127 @@
+package upstream
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreakerStateMachine(t *testing.T) {
+	b := NewBreaker(&BreakerSettings{Failures: 2, Cooldown: 10 * time.Second})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	allow := func(at time.Time) error {
+		t.Helper()
+		return b.Allow(at)
+	}
+
+	// Closed: one failure is not enough to open.
+	if err := allow(now); err != nil {
+		t.Fatalf("closed breaker rejected a call: %v", err)
+	}
+	b.Record(false, now)
+	if b.state != stateClosed {
+		t.Fatalf("state after one failure = %d, want closed", b.state)
+	}
+
+	// A success resets the count.
+	b.Record(true, now)
+	b.Record(false, now)
+	if b.state != stateClosed {
+		t.Fatalf("state after success and failure = %d, want closed", b.state)
+	}
+
+	// Consecutive failures open it.
+	b.Record(false, now)
+	if b.state != stateOpen {
+		t.Fatalf("state after %d failures = %d, want open", 2, b.state)
+	}
+
+	err := allow(now.Add(4 * time.Second))
+	var openErr *OpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("open breaker returned %v, want OpenError", err)
+	}
+	if openErr.RetryAfter != 6*time.Second {
+		t.Errorf("RetryAfter = %v, want 6s", openErr.RetryAfter)
+	}
+
+	// After the cooldown a single probe goes through.
+	if err := allow(now.Add(10 * time.Second)); err != nil {
+		t.Fatalf("probe after cooldown rejected: %v", err)
+	}
+	if b.state != stateHalfOpen {
+		t.Fatalf("state during probe = %d, want half-open", b.state)
+	}
+	if err := allow(now.Add(10 * time.Second)); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("second call during probe returned %v, want ErrCircuitOpen", err)
+	}
+
+	// A failed probe opens it again for a full cooldown.
+	b.Record(false, now.Add(11*time.Second))
+	if b.state != stateOpen {
+		t.Fatalf("state after failed probe = %d, want open", b.state)
+	}
+	if err := allow(now.Add(20 * time.Second)); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("call before the new cooldown returned %v, want ErrCircuitOpen", err)
+	}
+
+	// A successful probe closes it.
+	if err := allow(now.Add(21 * time.Second)); err != nil {
+		t.Fatalf("second probe rejected: %v", err)
+	}
+	b.Record(true, now.Add(21*time.Second))
+	if b.state != stateClosed || b.failures != 0 {
+		t.Fatalf("state after successful probe = %d with %d failures, want closed with 0", b.state, b.failures)
+	}
+}
+
+func TestBreakerAbandon(t *testing.T) {
+	b := NewBreaker(&BreakerSettings{Failures: 1, Cooldown: time.Second})
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Abandoning a call in the closed state changes nothing.
+	b.Record(false, now)
+	b.Record(true, now)
+	b.Abandon()
+	if b.state != stateClosed {
+		t.Fatalf("state after abandon while closed = %d, want closed", b.state)
+	}
+
+	b.Record(false, now)
+
+	if err := b.Allow(now.Add(time.Second)); err != nil {
+		t.Fatalf("probe rejected: %v", err)
+	}
+
+	// An abandoned probe frees the slot, the next call becomes the probe
+	// instead of waiting for an answer that never comes.
+	b.Abandon()
+	if b.state != stateOpen {
+		t.Fatalf("state after abandoned probe = %d, want open", b.state)
+	}
+	if err := b.Allow(now.Add(time.Second)); err != nil {
+		t.Fatalf("call after abandoned probe rejected: %v", err)
+	}
+	if b.state != stateHalfOpen {
+		t.Fatalf("state = %d, want half-open", b.state)
+	}
+}
+
+func TestBreakerSettingsDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings *BreakerSettings
+		want     BreakerSettings
+	}{
+		{name: "nil", settings: nil, want: BreakerSettings{Failures: DefaultBreakerFailures, Cooldown: DefaultBreakerCooldown}},
+		{name: "zero", settings: &BreakerSettings{}, want: BreakerSettings{Failures: DefaultBreakerFailures, Cooldown: DefaultBreakerCooldown}},
+		{name: "set", settings: &BreakerSettings{Failures: 3, Cooldown: time.Minute}, want: BreakerSettings{Failures: 3, Cooldown: time.Minute}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.settings.withDefaults(); got != tt.want {
+			t.Errorf("%s: withDefaults = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
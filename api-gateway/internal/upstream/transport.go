@@ -0,0 +1,173 @@
+package upstream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultTimeout = 10 * time.Second
+	DefaultRetries = 2
+
+	retryBaseDelay = 100 * time.Millisecond
+	retryMaxDelay  = 2 * time.Second
+
+	// maxRetryBody is the largest request body kept in memory for a retry.
+	// Larger bodies are streamed and sent only once.
+	maxRetryBody = 1 << 20
+)
+
+// Transport sends requests to one upstream through its breaker. Requests with
+// idempotent methods are retried up to Retries times on connection errors and
+// 502/503/504 answers, waiting a jittered, growing delay between attempts.
+type Transport struct {
+	Base    http.RoundTripper
+	Breaker *Breaker
+	Retries int
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	attempts := 1
+	if t.Retries > 0 && isIdempotent(req.Method) {
+		rewindable, err := makeRewindable(req)
+		if err != nil {
+			return nil, err
+		}
+		if rewindable {
+			attempts += t.Retries
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := t.Breaker.Allow(time.Now())
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := t.Base.RoundTrip(req)
+
+		if errors.Is(ctx.Err(), context.Canceled) {
+			t.Breaker.Abandon()
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+
+		// A body over the route limit is the fault of the client, not the
+		// upstream.
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			t.Breaker.Abandon()
+			return nil, err
+		}
+
+		failed := err != nil || isUnavailable(resp.StatusCode)
+		t.Breaker.Record(!failed, time.Now())
+
+		// An expired route timeout leaves no time for another attempt.
+		if !failed || attempt == attempts || ctx.Err() != nil {
+			return resp, err
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		err = sleep(ctx, backoff(attempt))
+		if err != nil {
+			return nil, err
+		}
+
+		req, err = rewind(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func isUnavailable(status int) bool {
+	return status == http.StatusBadGateway ||
+		status == http.StatusServiceUnavailable ||
+		status == http.StatusGatewayTimeout
+}
+
+// makeRewindable lets the body be sent again. It buffers small bodies and
+// reports false for large ones, which must not be retried.
+func makeRewindable(req *http.Request) (bool, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return true, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(req.Body, maxRetryBody+1))
+	if err != nil {
+		return false, err
+	}
+
+	if len(data) > maxRetryBody {
+		req.Body = readCloser{io.MultiReader(bytes.NewReader(data), req.Body), req.Body}
+		return false, nil
+	}
+
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	return true, nil
+}
+
+func rewind(req *http.Request) (*http.Request, error) {
+	next := req.Clone(req.Context())
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		next.Body = body
+	}
+
+	return next, nil
+}
+
+// backoff is the full-jitter delay before attempt+1: a random duration up to
+// an exponentially growing cap, so retries of many clients spread out.
+func backoff(attempt int) time.Duration {
+	limit := min(retryMaxDelay, retryBaseDelay<<(attempt-1))
+	return rand.N(limit) + time.Millisecond
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,174 @@
+package upstream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRoundTripper answers with the next of its statuses, a zero status is a
+// connection error. It records the body every attempt received.
+type fakeRoundTripper struct {
+	statuses []int
+	bodies   []string
+}
+
+var errConnRefused = errors.New("connection refused")
+
+func (f *fakeRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	if req.Body != nil {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = string(data)
+	}
+	f.bodies = append(f.bodies, body)
+
+	status := http.StatusOK
+	if len(f.bodies) <= len(f.statuses) {
+		status = f.statuses[len(f.bodies)-1]
+	}
+	if status == 0 {
+		return nil, errConnRefused
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+func newTestTransport(base http.RoundTripper) *Transport {
+	return &Transport{
+		Base:    base,
+		Breaker: NewBreaker(&BreakerSettings{Failures: 100}),
+		Retries: 2,
+	}
+}
+
+func TestTransportRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		statuses   []int
+		wantStatus int
+		wantErr    error
+		wantCalls  int
+	}{
+		{name: "get recovers", method: "GET", statuses: []int{503, 0}, wantStatus: 200, wantCalls: 3},
+		{name: "get gives up", method: "GET", statuses: []int{502, 503, 504}, wantStatus: 504, wantCalls: 3},
+		{name: "connection error is the last answer", method: "GET", statuses: []int{503, 503, 0}, wantErr: errConnRefused, wantCalls: 3},
+		{name: "put body is sent again", method: "PUT", body: `{"title":"Alien"}`, statuses: []int{503}, wantStatus: 200, wantCalls: 2},
+		{name: "delete", method: "DELETE", statuses: []int{0}, wantStatus: 200, wantCalls: 2},
+		{name: "post is not retried", method: "POST", body: `{"title":"Alien"}`, statuses: []int{503}, wantStatus: 503, wantCalls: 1},
+		{name: "patch is not retried", method: "PATCH", statuses: []int{0}, wantErr: errConnRefused, wantCalls: 1},
+		{name: "internal server error is not retried", method: "GET", statuses: []int{500, 404}, wantStatus: 500, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &fakeRoundTripper{statuses: tt.statuses}
+			transport := newTestTransport(base)
+
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, "http://movies/api/movies", body)
+			// Bodies from a server request have no GetBody.
+			req.GetBody = nil
+
+			resp, err := transport.RoundTrip(req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RoundTrip error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if len(base.bodies) != tt.wantCalls {
+				t.Fatalf("upstream called %d times, want %d", len(base.bodies), tt.wantCalls)
+			}
+			for i, got := range base.bodies {
+				if got != tt.body {
+					t.Errorf("attempt %d body = %q, want %q", i+1, got, tt.body)
+				}
+			}
+		})
+	}
+}
+
+func TestTransportLargeBodyIsSentOnce(t *testing.T) {
+	base := &fakeRoundTripper{statuses: []int{503}}
+	transport := newTestTransport(base)
+
+	body := strings.Repeat("a", maxRetryBody+1)
+	req := httptest.NewRequest("PUT", "http://movies/api/movies/1", strings.NewReader(body))
+	req.GetBody = nil
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", resp.StatusCode)
+	}
+	if len(base.bodies) != 1 {
+		t.Fatalf("upstream called %d times, want 1", len(base.bodies))
+	}
+	if base.bodies[0] != body {
+		t.Errorf("upstream got %d bytes, want %d", len(base.bodies[0]), len(body))
+	}
+}
+
+func TestTransportOpenBreaker(t *testing.T) {
+	base := &fakeRoundTripper{}
+	transport := newTestTransport(base)
+	transport.Breaker = NewBreaker(&BreakerSettings{Failures: 1, Cooldown: time.Minute})
+	transport.Breaker.Record(false, time.Now())
+
+	_, err := transport.RoundTrip(httptest.NewRequest("GET", "http://movies/api/movies", nil))
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("RoundTrip error = %v, want ErrCircuitOpen", err)
+	}
+	if len(base.bodies) != 0 {
+		t.Fatalf("upstream called %d times through an open breaker", len(base.bodies))
+	}
+}
+
+// cancelingRoundTripper cancels the request as if the client went away while
+// the upstream was answering.
+type cancelingRoundTripper struct {
+	cancel context.CancelFunc
+}
+
+func (c *cancelingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.cancel()
+	return nil, req.Context().Err()
+}
+
+func TestTransportCanceledCallIsAbandoned(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	transport := newTestTransport(&cancelingRoundTripper{cancel: cancel})
+	transport.Breaker = NewBreaker(&BreakerSettings{Failures: 1})
+
+	req := httptest.NewRequest("GET", "http://movies/api/movies", nil).WithContext(ctx)
+
+	_, err := transport.RoundTrip(req)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("RoundTrip error = %v, want context.Canceled", err)
+	}
+	if transport.Breaker.state != stateClosed || transport.Breaker.failures != 0 {
+		t.Fatalf("canceled call counted as a failure: state %d, %d failures", transport.Breaker.state, transport.Breaker.failures)
+	}
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Identity headers carry the verified caller to upstream services. Services
+// can trust them only because the gateway drops client-supplied copies
+// before routing, see StripIdentityHeaders.
+const (
+	UserIDHeader   = "X-User-ID"
+	UserRoleHeader = "X-User-Role"
+	ClientIDHeader = "X-Client-ID"
+)
+
+func StripIdentityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del(UserIDHeader)
+		r.Header.Del(UserRoleHeader)
+		r.Header.Del(ClientIDHeader)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// setIdentityHeaders forwards the caller. Tokens of the OAuth client
+// credentials grant have no user, only the client ID.
+func setIdentityHeaders(r *http.Request, claims *Claims) {
+	if claims.UserID != 0 {
+		r.Header.Set(UserIDHeader, strconv.FormatUint(uint64(claims.UserID), 10))
+		r.Header.Set(UserRoleHeader, claims.Role)
+	}
+
+	if claims.ClientID != "" {
+		r.Header.Set(ClientIDHeader, claims.ClientID)
+	}
+}
@@ -1,10 +1,13 @@
 package middleware
 
 import (
+	"api-gateway/internal/apikey"
+	"api-gateway/internal/jwks"
+	"api-gateway/internal/revocation"
 	"api-gateway/pkg/res"
 	"context"
 	"net/http"
-	"os"
+	"slices"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -12,58 +15,148 @@ import (
 
 type key string
 
-const RoleKey key = "userRole"
+const (
+	RoleKey   key = "userRole"
+	UserIDKey key = "userID"
+)
 
-func CheckRoleAndMethod(requiredRole string, allowedMethods []string, next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		secret := os.Getenv("JWT_SECRET")
+type Claims struct {
+	UserID      uint     `json:"userID"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	SessionID   string   `json:"sid"`
+	ClientID    string   `json:"client_id"`
+	jwt.RegisteredClaims
+}
+
+// MethodPermissions maps every method a route accepts to the permission it
+// requires. An empty permission only requires a valid token or API key.
+type MethodPermissions map[string]string
+
+type AuthMiddleware struct {
+	Keys            *jwks.Cache
+	RevocationStore *revocation.Store
+	APIKeys         *apikey.Store
+}
 
+func NewAuthMiddleware(keys *jwks.Cache, revocationStore *revocation.Store, apiKeys *apikey.Store) *AuthMiddleware {
+	return &AuthMiddleware{
+		Keys:            keys,
+		RevocationStore: revocationStore,
+		APIKeys:         apiKeys,
+	}
+}
+
+func (m *AuthMiddleware) RequirePermissions(permissions MethodPermissions, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
 			http.Error(w, "missing auth header", http.StatusUnauthorized)
 			return
 		}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+		var (
+			claims *Claims
+			ok     bool
+		)
 
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
-
-		if err != nil || !token.Valid {
-			res.ErrResJson(w, "invalid token", http.StatusUnauthorized)
-			return
+		if strings.HasPrefix(authHeader, "ApiKey ") {
+			claims, ok = m.authenticateAPIKey(w, r, strings.TrimPrefix(authHeader, "ApiKey "))
+		} else {
+			claims, ok = m.authenticateBearer(w, r, strings.TrimPrefix(authHeader, "Bearer "))
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
-			res.ErrResJson(w, "invalid claims", http.StatusUnauthorized)
 			return
 		}
 
-		role, ok := claims["role"].(string)
-
-		if !ok || (requiredRole == "admin" && role != "admin") {
-			res.ErrResJson(w, "access denied", http.StatusForbidden)
+		permission, ok := permissions[r.Method]
+		if !ok {
+			res.ErrResJson(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		if !isMethodAllowed(r.Method, allowedMethods) {
-			res.ErrResJson(w, "method not allowed", http.StatusMethodNotAllowed)
+		if permission != "" && !slices.Contains(claims.Permissions, permission) {
+			res.ErrResJson(w, "access denied: "+permission+" required", http.StatusForbidden)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), RoleKey, role)
+		ctx := context.WithValue(r.Context(), RoleKey, claims.Role)
+		ctx = context.WithValue(ctx, UserIDKey, claims.UserID)
+
+		r = r.WithContext(ctx)
+		setIdentityHeaders(r, claims)
 
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r)
 	})
 }
 
-func isMethodAllowed(requestMethod string, allowedMethods []string) bool {
-	for _, method := range allowedMethods {
-		if method == requestMethod {
-			return true
+// RequireRoles limits a route to callers with one of roles. It reads the role
+// stored by RequirePermissions, so it must be wrapped by it.
+func RequireRoles(roles []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value(RoleKey).(string)
+		if !slices.Contains(roles, role) {
+			res.ErrResJson(w, "access denied: role "+strings.Join(roles, " or ")+" required", http.StatusForbidden)
+			return
 		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *AuthMiddleware) authenticateBearer(w http.ResponseWriter, r *http.Request, tokenStr string) (*Claims, bool) {
+	claims := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenStr, claims, m.Keys.Keyfunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+
+	if err != nil || !token.Valid || claims.ID == "" || claims.IssuedAt == nil {
+		res.ErrResJson(w, "invalid token", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	revoked, err := m.RevocationStore.IsRevoked(r.Context(), claims.ID, claims.UserID, claims.SessionID, claims.ClientID, claims.IssuedAt.Time)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if revoked {
+		res.ErrResJson(w, "token revoked", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// authenticateAPIKey accepts a key only for methods its scopes cover: read
+// for safe methods, write for everything else. Role and permissions are the
+// owner's current ones, so demoting the owner also narrows the key.
+func (m *AuthMiddleware) authenticateAPIKey(w http.ResponseWriter, r *http.Request, key string) (*Claims, bool) {
+	identity, err := m.APIKeys.Lookup(r.Context(), key)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if identity == nil {
+		res.ErrResJson(w, "invalid api key", http.StatusUnauthorized)
+		return nil, false
 	}
-	return false
+
+	scope := apikey.ScopeWrite
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		scope = apikey.ScopeRead
+	}
+
+	if !identity.HasScope(scope) {
+		res.ErrResJson(w, "api key lacks "+scope+" scope", http.StatusForbidden)
+		return nil, false
+	}
+
+	return &Claims{UserID: identity.UserID, Role: identity.Role, Permissions: identity.Permissions}, true
 }
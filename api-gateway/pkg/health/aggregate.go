@@ -0,0 +1,121 @@
+package health
+
+import (
+	"api-gateway/pkg/res"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StatusDegraded means the gateway works but some upstream is not ready.
+const StatusDegraded = "degraded"
+
+// Upstreams lists the services behind the gateway by name and address.
+type Upstreams interface {
+	Upstreams() map[string]string
+}
+
+type AggregateResponse struct {
+	Status    string            `json:"status"`
+	Checks    map[string]string `json:"checks,omitempty"`
+	Upstreams map[string]string `json:"upstreams"`
+}
+
+// Aggregate answers with the readiness of the gateway and of every upstream,
+// asked through its GET /readyz. The answer is 200 only if all of them are
+// ready, 503 otherwise. The result is reused for ttl, and requests arriving
+// while it is computed wait for it, so the endpoint cannot be used to flood
+// the upstreams with probes.
+func Aggregate(checks map[string]Check, upstreams Upstreams, client *http.Client, ttl time.Duration) http.Handler {
+	a := &aggregator{checks: checks, upstreams: upstreams, client: client, ttl: ttl}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := a.get(r.Context())
+
+		status := http.StatusOK
+		if resp.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+
+		res.ResJson(w, resp, status)
+	})
+}
+
+type aggregator struct {
+	checks    map[string]Check
+	upstreams Upstreams
+	client    *http.Client
+	ttl       time.Duration
+
+	mu        sync.Mutex
+	last      *AggregateResponse
+	checkedAt time.Time
+}
+
+func (a *aggregator) get(ctx context.Context) *AggregateResponse {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.last != nil && time.Since(a.checkedAt) < a.ttl {
+		return a.last
+	}
+
+	// The result is shared, a caller going away must not cut it short.
+	a.last = a.check(context.WithoutCancel(ctx))
+	a.checkedAt = time.Now()
+
+	return a.last
+}
+
+func (a *aggregator) check(ctx context.Context) *AggregateResponse {
+	upstreamChecks := make(map[string]Check)
+	for name, address := range a.upstreams.Upstreams() {
+		upstreamChecks[name] = readyz(a.client, address)
+	}
+
+	upstreamsDone := make(chan *Response, 1)
+	go func() {
+		upstreamsDone <- Run(ctx, upstreamChecks)
+	}()
+
+	own := Run(ctx, a.checks)
+	upstreamsResp := <-upstreamsDone
+
+	resp := &AggregateResponse{
+		Status:    StatusOK,
+		Checks:    own.Checks,
+		Upstreams: upstreamsResp.Checks,
+	}
+
+	switch {
+	case own.Status != StatusOK:
+		resp.Status = StatusUnavailable
+	case upstreamsResp.Status != StatusOK:
+		resp.Status = StatusDegraded
+	}
+
+	return resp
+}
+
+func readyz(client *http.Client, address string) Check {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+address+"/readyz", nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("readyz answered %d", resp.StatusCode)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,175 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	RequestIDHeader = "X-Request-ID"
+	userIDHeader    = "X-User-ID"
+)
+
+const maxRequestIDLength = 128
+
+type key string
+
+const requestIDKey key = "requestID"
+
+// Setup makes a JSON logger the default for slog and the log package. Records
+// logged with a request context carry its request ID. LOG_LEVEL sets the
+// minimum level (debug, info, warn, error).
+func Setup(service string) {
+	var level slog.Level
+
+	err := level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL")))
+	if err != nil {
+		level = slog.LevelInfo
+	}
+
+	handler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: level})
+
+	slog.SetDefault(slog.New(&contextHandler{handler}).With("service", service))
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// Routes finds the route pattern of a request, *http.ServeMux implements it.
+// The pattern the mux records while serving is only visible to the innermost
+// middleware, so the access log looks it up on its own.
+type Routes interface {
+	Handler(r *http.Request) (http.Handler, string)
+}
+
+// AccessLog keeps the request ID of the caller or assigns a new one, passes
+// it on in the request header and the context, returns it to the client and
+// logs every request once it is served. The user ID comes from the identity
+// header the gateway sets after authentication.
+func AccessLog(routes Routes, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		_, route := routes.Handler(r)
+
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+			r.Header.Set(RequestIDHeader, id)
+		}
+
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		r = r.WithContext(ctx)
+
+		rec := &statusRecorder{ResponseWriter: w, requestID: id}
+
+		next.ServeHTTP(rec, r)
+
+		rec.ensureHeader()
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		slog.LogAttrs(ctx, level, "request",
+			slog.String("method", r.Method),
+			slog.String("route", route),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("user_id", r.Header.Get(userIDHeader)),
+		)
+	})
+}
+
+// statusRecorder remembers the status and sets the request ID header right
+// before the header is written, replacing any copy a proxied upstream sent.
+type statusRecorder struct {
+	http.ResponseWriter
+	requestID   string
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) ensureHeader() {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+
+	// Informational answers such as 103 Early Hints precede the real one.
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		r.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	r.wroteHeader = true
+	r.status = status
+	r.Header().Set(RequestIDHeader, r.requestID)
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.ensureHeader()
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach Flush of the real writer, which
+// the reverse proxy relies on for streamed answers.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		ok := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == ':'
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -2,83 +2,273 @@ package main
 
 import (
 	"auth-service/internal/handlers"
+	"auth-service/internal/middleware"
 	"auth-service/internal/postgres"
 	"auth-service/internal/repository"
 	"auth-service/internal/service"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/health"
+	"auth-service/pkg/jwt"
+	"auth-service/pkg/logger"
+	"auth-service/pkg/mail"
+	"auth-service/pkg/metrics"
+	"auth-service/pkg/password"
+	"auth-service/pkg/tracing"
 	"context"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 )
 
 func Run() error {
 
+	logger.Setup("auth-service")
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "auth-service")
+	if err != nil {
+		return err
+	}
+
+	defer shutdownTracing(context.Background())
+
 	router := http.NewServeMux()
 
 	db, err := postgres.NewConnectDb()
 
 	if err != nil {
-		log.Panicln(err)
+		return err
+	}
+
+	slog.Info("Postgres DB connected")
+
+	metrics.RegisterDB(db.DB, "postgres")
+
+	signingAlgorithm := os.Getenv("JWT_SIGNING_ALG")
+	if signingAlgorithm == "" {
+		signingAlgorithm = jwt.AlgEdDSA
+	}
+
+	keyCipher, err := jwt.NewKeyCipher(os.Getenv("SIGNING_KEY_ENCRYPTION_KEY"))
+	if err != nil {
+		return err
+	}
+
+	signingKeyRepository := repository.NewSigningKeyRepository(db)
+	keyService := service.NewKeyService(
+		signingKeyRepository,
+		keyCipher,
+		signingAlgorithm,
+		durationEnv("JWT_KEY_GRACE_PERIOD", time.Hour),
+		durationEnv("JWT_KEY_ROTATION_INTERVAL", 0),
+		durationEnv("JWT_KEY_PREPUBLISH_PERIOD", consts.DefaultKeyPrePublishPeriod),
+	)
+
+	err = keyService.Load(context.Background())
+	if err != nil {
+		return err
 	}
 
-	log.Println("Postgres DB connected: ", db)
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	go keyService.Run(backgroundCtx, time.Minute)
+
+	passwordPolicy, err := password.NewPolicy(
+		intEnv("PASSWORD_MIN_LENGTH", consts.DefaultPasswordMinLength),
+		consts.PasswordMaxLength,
+		os.Getenv("PASSWORD_DENYLIST_PATH"),
+	)
+	if err != nil {
+		return err
+	}
 
 	authRepository := repository.NewAuthRepository(db)
-	authService := service.NewAuthService(authRepository)
+	refreshTokenRepository := repository.NewRefreshTokenRepository(db)
+	revocationRepository := repository.NewRevocationRepository(db)
+	sessionRepository := repository.NewSessionRepository(db)
+	loginAttemptRepository := repository.NewLoginAttemptRepository(db)
+	throttleService := service.NewThrottleService(loginAttemptRepository)
+	totpRepository := repository.NewTOTPRepository(db)
+	totpService := service.NewTOTPService(authRepository, totpRepository)
+	authService := service.NewAuthService(
+		authRepository,
+		refreshTokenRepository,
+		revocationRepository,
+		sessionRepository,
+		keyService,
+		throttleService,
+		totpService,
+		passwordPolicy,
+	)
+	authMiddleware := middleware.NewAuthMiddleware(authService)
+
+	handlers.NewAuthHandler(router, authService, authMiddleware)
+	handlers.NewKeyHandler(router, keyService, authMiddleware)
+	handlers.NewTOTPHandler(router, authService, totpService, authMiddleware)
+
+	sessionService := service.NewSessionService(sessionRepository)
+
+	handlers.NewSessionHandler(router, sessionService, authMiddleware)
 
-	handlers.NewAuthHandler(router, authService)
+	roleRepository := repository.NewRoleRepository(db)
+	roleService := service.NewRoleService(roleRepository)
 
-	log.Println("Auth repository initialized", authRepository)
-	log.Println("Auth service initialized", authService)
+	handlers.NewRoleHandler(router, roleService, authMiddleware)
+
+	userRepository := repository.NewUserRepository(db)
+	userService := service.NewUserService(authRepository, userRepository, revocationRepository, throttleService, roleService)
+
+	handlers.NewAdminHandler(router, userService, authMiddleware)
+
+	var mailSender mail.Sender = mail.NewLogSender()
+	if os.Getenv("MAIL_SENDER") == "file" {
+		mailPath := os.Getenv("MAIL_FILE_PATH")
+		if mailPath == "" {
+			mailPath = "mail.log"
+		}
+		mailSender = mail.NewFileSender(mailPath)
+	}
+
+	resetURL := os.Getenv("PASSWORD_RESET_URL")
+	if resetURL == "" {
+		resetURL = "http://localhost:8080/reset-password"
+	}
+
+	passwordResetRepository := repository.NewPasswordResetRepository(db)
+	passwordService := service.NewPasswordService(
+		authRepository,
+		passwordResetRepository,
+		revocationRepository,
+		throttleService,
+		mailSender,
+		passwordPolicy,
+		resetURL,
+	)
+
+	handlers.NewPasswordHandler(router, passwordService, authMiddleware)
+
+	invitationRepository := repository.NewInvitationRepository(db)
+	invitationService := service.NewInvitationService(invitationRepository, roleService)
+
+	handlers.NewInvitationHandler(router, invitationService, authMiddleware)
+
+	apiKeyRepository := repository.NewAPIKeyRepository(db)
+	apiKeyService := service.NewAPIKeyService(authRepository, apiKeyRepository, roleService)
+
+	handlers.NewAPIKeyHandler(router, apiKeyService, authMiddleware)
+
+	accountRepository := repository.NewAccountRepository(db)
+	accountService := service.NewAccountService(
+		authRepository,
+		accountRepository,
+		apiKeyRepository,
+		userRepository,
+		throttleService,
+		totpService,
+		mailSender,
+		durationEnv("ACCOUNT_DELETION_DELAY", consts.DefaultAccountDeletionDelay),
+	)
+
+	handlers.NewAccountHandler(router, accountService, authMiddleware)
+
+	go accountService.Run(backgroundCtx, time.Hour)
+
+	cleanupService := service.NewCleanupService(repository.NewCleanupRepository(db))
+
+	go cleanupService.Run(backgroundCtx, time.Hour)
+
+	oauthRepository := repository.NewOAuthRepository(db)
+	oauthService := service.NewOAuthService(oauthRepository, authService)
+
+	handlers.NewOAuthHandler(router, oauthService, authMiddleware)
+
+	adminUsername := os.Getenv("BOOTSTRAP_ADMIN_USERNAME")
+	adminPassword := os.Getenv("BOOTSTRAP_ADMIN_PASSWORD")
+
+	if adminUsername != "" && adminPassword != "" {
+		created, err := authService.BootstrapAdmin(context.Background(), adminUsername, adminPassword)
+		if err != nil {
+			slog.Error("Bootstrap admin error", "error", err)
+		}
+		if created {
+			slog.Info("Bootstrap admin created", "username", adminUsername)
+		}
+	}
+
+	health.Register(router, map[string]health.Check{"postgres": db.PingContext})
 
 	server := http.Server{
 		Addr:    ":8001",
-		Handler: router,
+		Handler: metrics.Instrument(router, logger.AccessLog(router, tracing.Handler("auth-service", router))),
 	}
 
+	metricsServer := metrics.Serve(os.Getenv("METRICS_ADDR"))
+
 	quit := make(chan os.Signal, 1)
 
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		log.Println("Auth microservice start on port 8001")
+		slog.Info("Auth microservice started", "addr", server.Addr)
 		err := server.ListenAndServe()
 		if err != nil && err != http.ErrServerClosed {
-			log.Fatalf("ListenAndServe error: %v", err)
+			slog.Error("ListenAndServe error", "error", err)
+			os.Exit(1)
 		}
 	}()
 
 	<-quit
 
-	log.Println("Shutting down auth microservice...")
+	slog.Info("Shutting down auth microservice")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 
 	defer cancel()
 
+	metricsServer.Shutdown(ctx)
+
 	err = server.Shutdown(ctx)
 
 	if err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		slog.Error("Server forced to shutdown", "error", err)
+		os.Exit(1)
 	}
 
 	err = db.Close()
 
 	if err != nil {
-		log.Printf("Error closing database: %v", err)
+		slog.Error("Error closing database", "error", err)
 	}
 
-	log.Println("Auth microservice stopped gracefully")
+	slog.Info("Auth microservice stopped gracefully")
 
 	return nil
 }
 
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func intEnv(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
 func main() {
 	err := Run()
 	if err != nil {
-		log.Fatalf("Auth microservice failed: %v", err)
+		slog.Error("Auth microservice failed", "error", err)
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,351 @@
+// Command oauth-test-client walks through the OAuth2 flows of auth-service
+// against a running gateway, playing both the third-party client and the
+// user's browser:
+//
+//	go run ./cmd/oauth-test-client -client-id mlc_... -client-secret ... \
+//		-username alice -password 'correct horse battery'
+//
+// It signs the user in, runs the authorization code flow with PKCE through
+// the consent step, receives the code on a local callback, exchanges and
+// refreshes it, calls the movies API with the token and, for confidential
+// clients, finally runs the client credentials grant.
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type config struct {
+	gateway      string
+	clientID     string
+	clientSecret string
+	redirectURI  string
+	scope        string
+	username     string
+	password     string
+	deny         bool
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+type authorizeResponse struct {
+	Client struct {
+		Name string `json:"name"`
+	} `json:"client"`
+	Scopes          []string `json:"scopes"`
+	ConsentRequired bool     `json:"consent_required"`
+	RedirectTo      string   `json:"redirect_to"`
+}
+
+func main() {
+	var cfg config
+
+	flag.StringVar(&cfg.gateway, "gateway", "http://localhost:8080", "gateway base URL")
+	flag.StringVar(&cfg.clientID, "client-id", "", "registered client ID")
+	flag.StringVar(&cfg.clientSecret, "client-secret", "", "client secret, empty for public clients")
+	flag.StringVar(&cfg.redirectURI, "redirect-uri", "http://127.0.0.1:8765/callback", "registered redirect URI, served locally")
+	flag.StringVar(&cfg.scope, "scope", "", "space-separated scopes, empty for all the client may have")
+	flag.StringVar(&cfg.username, "username", "", "user that authorizes the client")
+	flag.StringVar(&cfg.password, "password", "", "password of the user")
+	flag.BoolVar(&cfg.deny, "deny", false, "deny consent instead of approving it")
+	flag.Parse()
+
+	if cfg.clientID == "" {
+		log.Fatal("-client-id is required")
+	}
+
+	if cfg.username != "" {
+		err := authorizationCodeFlow(&cfg)
+		if err != nil {
+			log.Fatalf("Authorization code flow failed: %v", err)
+		}
+	}
+
+	if cfg.clientSecret != "" {
+		err := clientCredentialsFlow(&cfg)
+		if err != nil {
+			log.Fatalf("Client credentials flow failed: %v", err)
+		}
+	}
+}
+
+func authorizationCodeFlow(cfg *config) error {
+	callback, err := url.Parse(cfg.redirectURI)
+	if err != nil {
+		return err
+	}
+
+	results, stop, err := serveCallback(callback)
+	if err != nil {
+		return err
+	}
+
+	defer stop()
+
+	userToken, err := login(cfg)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Signed in as %s", cfg.username)
+
+	verifier := randomString()
+	challenge := sha256.Sum256([]byte(verifier))
+	state := randomString()
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {cfg.clientID},
+		"redirect_uri":          {cfg.redirectURI},
+		"scope":                 {cfg.scope},
+		"state":                 {state},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	authorizeURL := cfg.gateway + "/api/auth/oauth/authorize?" + query.Encode()
+
+	var authorization authorizeResponse
+
+	err = call(http.MethodGet, authorizeURL, userToken, nil, &authorization)
+	if err != nil {
+		return err
+	}
+
+	if authorization.ConsentRequired {
+		log.Printf("%s asks for %s, answering approve=%t", authorization.Client.Name, strings.Join(authorization.Scopes, " "), !cfg.deny)
+
+		decision, _ := json.Marshal(map[string]bool{"approve": !cfg.deny})
+
+		err = call(http.MethodPost, authorizeURL, userToken, decision, &authorization)
+		if err != nil {
+			return err
+		}
+	} else {
+		log.Printf("Consent for %s was given before", strings.Join(authorization.Scopes, " "))
+	}
+
+	// The browser would follow this redirect; the callback server receives it.
+	resp, err := http.Get(authorization.RedirectTo)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	result := <-results
+
+	if result.Get("state") != state {
+		return errors.New("state mismatch on callback")
+	}
+
+	if cfg.deny && result.Get("error") == "access_denied" {
+		log.Print("Client received access_denied as expected")
+		return nil
+	}
+
+	if result.Get("error") != "" {
+		return fmt.Errorf("authorization failed: %s: %s", result.Get("error"), result.Get("error_description"))
+	}
+
+	log.Printf("Received authorization code on %s", callback.Path)
+
+	tokens, err := requestToken(cfg, url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {result.Get("code")},
+		"redirect_uri":  {cfg.redirectURI},
+		"code_verifier": {verifier},
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Access token issued, scope %q, expires in %ds", tokens.Scope, tokens.ExpiresIn)
+
+	err = callMovies(cfg, tokens.AccessToken)
+	if err != nil {
+		return err
+	}
+
+	if tokens.RefreshToken == "" {
+		return nil
+	}
+
+	tokens, err = requestToken(cfg, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {tokens.RefreshToken},
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Token refreshed, scope %q", tokens.Scope)
+
+	return callMovies(cfg, tokens.AccessToken)
+}
+
+func clientCredentialsFlow(cfg *config) error {
+	tokens, err := requestToken(cfg, url.Values{
+		"grant_type": {"client_credentials"},
+		"scope":      {cfg.scope},
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Client credentials token issued, scope %q", tokens.Scope)
+
+	return callMovies(cfg, tokens.AccessToken)
+}
+
+func login(cfg *config) (string, error) {
+	body, _ := json.Marshal(map[string]string{"username": cfg.username, "password": cfg.password})
+
+	var data struct {
+		Token       string `json:"token"`
+		MFARequired bool   `json:"mfa_required"`
+	}
+
+	err := call(http.MethodPost, cfg.gateway+"/api/auth/login", "", body, &data)
+	if err != nil {
+		return "", err
+	}
+
+	if data.MFARequired {
+		return "", errors.New("the user has two-factor authentication enabled, use another test user")
+	}
+
+	return data.Token, nil
+}
+
+func requestToken(cfg *config, form url.Values) (*tokenResponse, error) {
+	form.Set("client_id", cfg.clientID)
+
+	req, err := http.NewRequest(http.MethodPost, cfg.gateway+"/api/auth/oauth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if cfg.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(cfg.clientID), url.QueryEscape(cfg.clientSecret))
+	}
+
+	var tokens tokenResponse
+
+	err = do(req, &tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tokens, nil
+}
+
+func callMovies(cfg *config, accessToken string) error {
+	var movies json.RawMessage
+
+	err := call(http.MethodGet, cfg.gateway+"/api/movies", accessToken, nil, &movies)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("GET /api/movies with the token: %d bytes", len(movies))
+
+	return nil
+}
+
+func call(method string, target string, accessToken string, body []byte, out any) error {
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	return do(req, out)
+}
+
+func do(req *http.Request, out any) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Path, resp.Status, bytes.TrimSpace(data))
+	}
+
+	return json.Unmarshal(data, out)
+}
+
+// serveCallback listens on the redirect URI and hands over the query of the
+// first request it receives.
+func serveCallback(callback *url.URL) (<-chan url.Values, func(), error) {
+	listener, err := net.Listen("tcp", callback.Host)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make(chan url.Values, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(callback.Path, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case results <- r.URL.Query():
+		default:
+		}
+		fmt.Fprintln(w, "Authorization finished, you can close this window.")
+	})
+
+	server := &http.Server{Handler: mux}
+
+	go server.Serve(listener)
+
+	stop := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}
+
+	return results, stop, nil
+}
+
+func randomString() string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"auth-service/internal/middleware"
+	"auth-service/internal/payload"
+	"auth-service/internal/service"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/req"
+	"auth-service/pkg/res"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type AccountHandler struct {
+	AccountService *service.AccountService
+}
+
+func NewAccountHandler(router *http.ServeMux, accountService *service.AccountService, authMiddleware *middleware.AuthMiddleware) {
+	handler := &AccountHandler{
+		AccountService: accountService,
+	}
+
+	router.Handle("GET /account/export", authMiddleware.Authenticate(http.HandlerFunc(handler.Export)))
+	router.Handle("POST /account/deletion", authMiddleware.Authenticate(http.HandlerFunc(handler.RequestDeletion)))
+	router.Handle("DELETE /account/deletion", authMiddleware.Authenticate(http.HandlerFunc(handler.CancelDeletion)))
+}
+
+func (h *AccountHandler) Export(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	data, err := h.AccountService.Export(ctx, claims.UserID)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="account-export.json"`)
+	w.Header().Set("Cache-Control", "no-store")
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func (h *AccountHandler) RequestDeletion(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := req.DecodedAndValidatedBody[payload.DeleteAccountPayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	scheduledAt, err := h.AccountService.RequestDeletion(ctx, claims.UserID, clientIP(r), &body)
+	if writeThrottled(w, err) {
+		return
+	}
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	data := &payload.DeleteAccountResponse{
+		DeletionScheduledAt: scheduledAt,
+		Message:             "Account deletion scheduled, cancel it before this date to keep the account",
+	}
+
+	res.ResJson(w, data, http.StatusAccepted)
+}
+
+func (h *AccountHandler) CancelDeletion(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	err := h.AccountService.CancelDeletion(ctx, claims.UserID)
+	if err != nil {
+		writeAccountError(w, err)
+		return
+	}
+
+	res.ResJson(w, &payload.AuthMessageResponse{Message: "Account deletion cancelled"}, http.StatusOK)
+}
+
+func writeAccountError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, consts.ErrInvalidCredentials):
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, consts.ErrUserNotFound), errors.Is(err, consts.ErrDeletionNotScheduled):
+		res.ErrResJson(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, consts.ErrLastAdmin):
+		res.ErrResJson(w, err.Error(), http.StatusConflict)
+	default:
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+	}
+}
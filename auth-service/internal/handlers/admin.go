@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"auth-service/internal/middleware"
+	"auth-service/internal/payload"
+	"auth-service/internal/service"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/req"
+	"auth-service/pkg/res"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type AdminHandler struct {
+	UserService *service.UserService
+}
+
+func NewAdminHandler(router *http.ServeMux, userService *service.UserService, authMiddleware *middleware.AuthMiddleware) {
+	handler := &AdminHandler{
+		UserService: userService,
+	}
+
+	admin := func(h http.HandlerFunc) http.Handler {
+		return authMiddleware.RequirePermission(consts.PermUsersManage, h)
+	}
+
+	router.Handle("GET /admin/users", admin(handler.ListUsers))
+	router.Handle("PATCH /admin/users/{id}/role", admin(handler.UpdateUserRole))
+	router.Handle("POST /admin/users/{id}/disable", admin(handler.DisableUser))
+	router.Handle("POST /admin/users/{id}/enable", admin(handler.EnableUser))
+	router.Handle("DELETE /admin/users/{id}", admin(handler.DeleteUser))
+	router.Handle("POST /admin/users/{id}/revoke-tokens", admin(handler.RevokeUserTokens))
+	router.Handle("POST /admin/users/{id}/unlock", admin(handler.UnlockUser))
+}
+
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	query := r.URL.Query()
+
+	page, err := parseUintParam(query.Get("page"))
+	if err != nil {
+		res.ErrResJson(w, "Invalid page", http.StatusBadRequest)
+		return
+	}
+
+	limit, err := parseUintParam(query.Get("limit"))
+	if err != nil {
+		res.ErrResJson(w, "Invalid limit", http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.UserService.List(ctx, query.Get("search"), page, limit)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func (h *AdminHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		res.ErrResJson(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	body, err := req.DecodedAndValidatedBody[payload.UpdateUserRolePayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	err = h.UserService.UpdateRole(ctx, claims.UserID, uint(id), body.Role)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	res.ResJson(w, &payload.AuthMessageResponse{Message: "User role was updated"}, http.StatusOK)
+}
+
+func (h *AdminHandler) DisableUser(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, true)
+}
+
+func (h *AdminHandler) EnableUser(w http.ResponseWriter, r *http.Request) {
+	h.setDisabled(w, r, false)
+}
+
+func (h *AdminHandler) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		res.ErrResJson(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	err = h.UserService.SetDisabled(ctx, claims.UserID, uint(id), disabled)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	message := "User was enabled"
+	if disabled {
+		message = "User was disabled"
+	}
+
+	res.ResJson(w, &payload.AuthMessageResponse{Message: message}, http.StatusOK)
+}
+
+func (h *AdminHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		res.ErrResJson(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	err = h.UserService.Delete(ctx, claims.UserID, uint(id))
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	res.ResJson(w, &payload.AuthMessageResponse{Message: "User was deleted"}, http.StatusOK)
+}
+
+func (h *AdminHandler) RevokeUserTokens(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	param := r.PathValue("id")
+
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		res.ErrResJson(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.UserService.RevokeTokens(ctx, uint(id))
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	data := &payload.AuthMessageResponse{
+		Message: "User tokens were revoked",
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func (h *AdminHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		res.ErrResJson(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.UserService.Unlock(ctx, uint(id))
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	res.ResJson(w, &payload.AuthMessageResponse{Message: "User was unlocked"}, http.StatusOK)
+}
+
+func writeUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, consts.ErrUserNotFound):
+		res.ErrResJson(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, consts.ErrCannotModifySelf), errors.Is(err, consts.ErrRoleNotFound):
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, consts.ErrLastAdmin):
+		res.ErrResJson(w, err.Error(), http.StatusConflict)
+	default:
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func parseUintParam(value string) (uint64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseUint(value, 10, 64)
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"auth-service/internal/middleware"
+	"auth-service/internal/payload"
+	"auth-service/internal/service"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/req"
+	"auth-service/pkg/res"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type APIKeyHandler struct {
+	APIKeyService *service.APIKeyService
+}
+
+func NewAPIKeyHandler(router *http.ServeMux, apiKeyService *service.APIKeyService, authMiddleware *middleware.AuthMiddleware) {
+	handler := &APIKeyHandler{
+		APIKeyService: apiKeyService,
+	}
+
+	admin := func(h http.HandlerFunc) http.Handler {
+		return authMiddleware.RequirePermission(consts.PermUsersManage, h)
+	}
+
+	router.Handle("POST /api-keys", authMiddleware.Authenticate(http.HandlerFunc(handler.CreateKey)))
+	router.Handle("GET /api-keys", authMiddleware.Authenticate(http.HandlerFunc(handler.ListKeys)))
+	router.Handle("DELETE /api-keys/{id}", authMiddleware.Authenticate(http.HandlerFunc(handler.RevokeKey)))
+
+	router.Handle("POST /admin/service-accounts", admin(handler.CreateServiceAccount))
+	router.Handle("POST /admin/service-accounts/{id}/api-keys", admin(handler.CreateServiceAccountKey))
+	router.Handle("GET /admin/service-accounts/{id}/api-keys", admin(handler.ListServiceAccountKeys))
+	router.Handle("DELETE /admin/service-accounts/{id}/api-keys/{keyID}", admin(handler.RevokeServiceAccountKey))
+}
+
+func (h *APIKeyHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := req.DecodedAndValidatedBody[payload.CreateAPIKeyPayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	data, err := h.APIKeyService.Create(ctx, claims.UserID, &body)
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusCreated)
+}
+
+func (h *APIKeyHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	data, err := h.APIKeyService.List(ctx, claims.UserID)
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func (h *APIKeyHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		res.ErrResJson(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	err = h.APIKeyService.Revoke(ctx, claims.UserID, uint(id))
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	res.ResJson(w, &payload.AuthMessageResponse{Message: "API key revoked"}, http.StatusOK)
+}
+
+func (h *APIKeyHandler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := req.DecodedAndValidatedBody[payload.CreateServiceAccountPayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.APIKeyService.CreateServiceAccount(ctx, &body)
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusCreated)
+}
+
+func (h *APIKeyHandler) CreateServiceAccountKey(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		res.ErrResJson(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	body, err := req.DecodedAndValidatedBody[payload.CreateAPIKeyPayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.APIKeyService.ServiceAccount(ctx, uint(id))
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	data, err := h.APIKeyService.Create(ctx, account.ID, &body)
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusCreated)
+}
+
+func (h *APIKeyHandler) ListServiceAccountKeys(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		res.ErrResJson(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.APIKeyService.ServiceAccount(ctx, uint(id))
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	data, err := h.APIKeyService.List(ctx, account.ID)
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func (h *APIKeyHandler) RevokeServiceAccountKey(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		res.ErrResJson(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	keyID, err := strconv.Atoi(r.PathValue("keyID"))
+	if err != nil {
+		res.ErrResJson(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.APIKeyService.ServiceAccount(ctx, uint(id))
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	err = h.APIKeyService.Revoke(ctx, account.ID, uint(keyID))
+	if err != nil {
+		writeAPIKeyError(w, err)
+		return
+	}
+
+	res.ResJson(w, &payload.AuthMessageResponse{Message: "API key revoked"}, http.StatusOK)
+}
+
+func writeAPIKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, consts.ErrUserNotFound), errors.Is(err, consts.ErrAPIKeyNotFound):
+		res.ErrResJson(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, consts.ErrNotServiceAccount), errors.Is(err, consts.ErrRoleNotFound):
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+	default:
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -1,26 +1,39 @@
 package handlers
 
 import (
+	"auth-service/internal/middleware"
+	"auth-service/internal/model"
 	"auth-service/internal/payload"
 	"auth-service/internal/service"
+	"auth-service/pkg/consts"
 	"auth-service/pkg/req"
 	"auth-service/pkg/res"
 	"context"
+	"errors"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
+const maxUserAgentLength = 512
+
 type AuthHandler struct {
 	AuthService *service.AuthService
 }
 
-func NewAuthHandler(router *http.ServeMux, authService *service.AuthService) {
+func NewAuthHandler(router *http.ServeMux, authService *service.AuthService, authMiddleware *middleware.AuthMiddleware) {
 	handler := &AuthHandler{
 		AuthService: authService,
 	}
 
 	router.HandleFunc("POST /register", handler.RegisterUser)
 	router.HandleFunc("POST /login", handler.LoginUser)
+	router.HandleFunc("POST /refresh", handler.RefreshToken)
+	router.Handle("POST /logout", authMiddleware.Authenticate(http.HandlerFunc(handler.LogoutUser)))
+	router.Handle("GET /me", authMiddleware.Authenticate(http.HandlerFunc(handler.Me)))
 
 }
 
@@ -36,6 +49,10 @@ func (h *AuthHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userID, err := h.AuthService.Register(ctx, &body)
+	if errors.Is(err, consts.ErrWeakPassword) {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -61,14 +78,125 @@ func (h *AuthHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := h.AuthService.Login(ctx, &body)
+	data, err := h.AuthService.Login(ctx, &body, clientInfo(r))
+	if writeThrottled(w, err) {
+		return
+	}
 	if err != nil {
 		res.ErrResJson(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	data := &payload.AuthLoginResponse{
-		Token: token,
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := req.DecodedAndValidatedBody[payload.AuthRefreshPayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.AuthService.Refresh(ctx, &body, clientInfo(r))
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func (h *AuthHandler) LogoutUser(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var body payload.AuthLogoutPayload
+
+	if r.ContentLength != 0 {
+		decoded, err := req.DecodedAndValidatedBody[payload.AuthLogoutPayload](r.Body)
+		if err != nil {
+			res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		body = decoded
+	}
+
+	err := h.AuthService.Logout(ctx, middleware.ClaimsFromContext(ctx), &body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := &payload.AuthMessageResponse{
+		Message: "Logged out",
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+// writeThrottled answers 429 with Retry-After if err is a *service.ThrottledError.
+func writeThrottled(w http.ResponseWriter, err error) bool {
+	var throttled *service.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	res.ErrResJson(w, err.Error(), http.StatusTooManyRequests)
+	return true
+}
+
+func clientInfo(r *http.Request) model.ClientInfo {
+	userAgent := []rune(r.UserAgent())
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	return model.ClientInfo{
+		IP:        clientIP(r),
+		UserAgent: string(userAgent),
+	}
+}
+
+// clientIP prefers the address the gateway appended to X-Forwarded-For, since
+// the connection itself always comes from the gateway. The header is only
+// trustworthy while auth-service is not reachable around the gateway, which
+// is why docker-compose does not publish its port.
+func clientIP(r *http.Request) string {
+	forwarded := r.Header.Get("X-Forwarded-For")
+	if forwarded != "" {
+		parts := strings.Split(forwarded, ",")
+		return strings.TrimSpace(parts[len(parts)-1])
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	data, err := h.AuthService.Me(ctx, claims.UserID)
+	if errors.Is(err, consts.ErrUserNotFound) {
+		res.ErrResJson(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
 	res.ResJson(w, data, http.StatusOK)
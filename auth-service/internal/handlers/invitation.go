@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"auth-service/internal/middleware"
+	"auth-service/internal/payload"
+	"auth-service/internal/service"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/req"
+	"auth-service/pkg/res"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type InvitationHandler struct {
+	InvitationService *service.InvitationService
+}
+
+func NewInvitationHandler(router *http.ServeMux, invitationService *service.InvitationService, authMiddleware *middleware.AuthMiddleware) {
+	handler := &InvitationHandler{
+		InvitationService: invitationService,
+	}
+
+	router.Handle("POST /admin/invitations", authMiddleware.RequirePermission(consts.PermUsersManage, http.HandlerFunc(handler.CreateInvitation)))
+	router.Handle("POST /invitations/redeem", authMiddleware.Authenticate(http.HandlerFunc(handler.RedeemInvitation)))
+}
+
+func (h *InvitationHandler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := req.DecodedAndValidatedBody[payload.CreateInvitationPayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	data, err := h.InvitationService.Create(ctx, claims.UserID, &body)
+	if errors.Is(err, consts.ErrRoleNotFound) {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusCreated)
+}
+
+func (h *InvitationHandler) RedeemInvitation(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := req.DecodedAndValidatedBody[payload.RedeemInvitationPayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	role, err := h.InvitationService.Redeem(ctx, claims.UserID, &body)
+	if errors.Is(err, consts.ErrInvitationInvalid) {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := &payload.RedeemInvitationResponse{
+		Role:    role,
+		Message: "Role granted, refresh your token to use it",
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
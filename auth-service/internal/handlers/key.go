@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"auth-service/internal/middleware"
+	"auth-service/internal/payload"
+	"auth-service/internal/service"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/res"
+	"context"
+	"net/http"
+	"time"
+)
+
+type KeyHandler struct {
+	KeyService *service.KeyService
+}
+
+func NewKeyHandler(router *http.ServeMux, keyService *service.KeyService, authMiddleware *middleware.AuthMiddleware) {
+	handler := &KeyHandler{
+		KeyService: keyService,
+	}
+
+	router.HandleFunc("GET /.well-known/jwks.json", handler.GetJWKS)
+	router.Handle("POST /admin/keys/rotate", authMiddleware.RequirePermission(consts.PermKeysManage, http.HandlerFunc(handler.RotateKey)))
+}
+
+func (h *KeyHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	res.ResJson(w, h.KeyService.JWKS(), http.StatusOK)
+}
+
+func (h *KeyHandler) RotateKey(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	key, err := h.KeyService.Rotate(ctx)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := &payload.RotateKeyResponse{
+		KID:         key.KID,
+		ActivatesAt: key.ActivatesAt,
+		Message:     "Signing key was rotated",
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"auth-service/internal/middleware"
+	"auth-service/internal/payload"
+	"auth-service/internal/service"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/req"
+	"auth-service/pkg/res"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type OAuthHandler struct {
+	OAuthService *service.OAuthService
+}
+
+func NewOAuthHandler(router *http.ServeMux, oauthService *service.OAuthService, authMiddleware *middleware.AuthMiddleware) {
+	handler := &OAuthHandler{
+		OAuthService: oauthService,
+	}
+
+	admin := func(h http.HandlerFunc) http.Handler {
+		return authMiddleware.RequirePermission(consts.PermUsersManage, h)
+	}
+
+	router.Handle("POST /admin/oauth/clients", admin(handler.CreateClient))
+	router.Handle("GET /admin/oauth/clients", admin(handler.ListClients))
+	router.Handle("DELETE /admin/oauth/clients/{id}", admin(handler.RevokeClient))
+
+	router.Handle("GET /oauth/authorize", authMiddleware.Authenticate(http.HandlerFunc(handler.Authorize)))
+	router.Handle("POST /oauth/authorize", authMiddleware.Authenticate(http.HandlerFunc(handler.Decide)))
+	router.HandleFunc("POST /oauth/token", handler.Token)
+	router.HandleFunc("POST /introspect", handler.Introspect)
+}
+
+func (h *OAuthHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := req.DecodedAndValidatedBody[payload.CreateOAuthClientPayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	data, err := h.OAuthService.RegisterClient(ctx, claims.UserID, &body)
+	if err != nil {
+		writeOAuthClientError(w, err)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusCreated)
+}
+
+func (h *OAuthHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	data, err := h.OAuthService.ListClients(ctx)
+	if err != nil {
+		writeOAuthClientError(w, err)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func (h *OAuthHandler) RevokeClient(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	err := h.OAuthService.RevokeClient(ctx, r.PathValue("id"))
+	if err != nil {
+		writeOAuthClientError(w, err)
+		return
+	}
+
+	res.ResJson(w, &payload.AuthMessageResponse{Message: "OAuth client revoked"}, http.StatusOK)
+}
+
+func (h *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	data, err := h.OAuthService.Authorize(ctx, claims.UserID, authorizeRequest(r.URL.Query()))
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func (h *OAuthHandler) Decide(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := req.DecodedAndValidatedBody[payload.AuthorizeDecisionPayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	data, err := h.OAuthService.Decide(ctx, claims.UserID, authorizeRequest(r.URL.Query()), body.Approve)
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+// Token is the RFC 6749 token endpoint. It takes a form body and accepts the
+// client credentials either as HTTP Basic auth or as form fields.
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	err := r.ParseForm()
+	if err != nil {
+		writeOAuthError(w, &service.OAuthError{Code: "invalid_request", Description: "invalid form body"})
+		return
+	}
+
+	body := payload.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		RefreshToken: r.PostForm.Get("refresh_token"),
+		Scope:        r.PostForm.Get("scope"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+	}
+
+	if id, secret, ok := r.BasicAuth(); ok {
+		body.ClientID, _ = url.QueryUnescape(id)
+		body.ClientSecret, _ = url.QueryUnescape(secret)
+	}
+
+	data, err := h.OAuthService.Token(ctx, &body, clientInfo(r))
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+// Introspect is the RFC 7662 endpoint for internal services. Callers
+// authenticate like at the token endpoint, with a client holding the
+// tokens:introspect scope.
+func (h *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	err := r.ParseForm()
+	if err != nil {
+		writeOAuthError(w, &service.OAuthError{Code: "invalid_request", Description: "invalid form body"})
+		return
+	}
+
+	clientID := r.PostForm.Get("client_id")
+	clientSecret := r.PostForm.Get("client_secret")
+
+	if id, secret, ok := r.BasicAuth(); ok {
+		clientID, _ = url.QueryUnescape(id)
+		clientSecret, _ = url.QueryUnescape(secret)
+	}
+
+	data, err := h.OAuthService.Introspect(ctx, clientID, clientSecret, r.PostForm.Get("token"))
+	if err != nil {
+		writeOAuthError(w, err)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func authorizeRequest(query url.Values) *payload.AuthorizeRequest {
+	return &payload.AuthorizeRequest{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}
+}
+
+// writeOAuthError answers in the RFC 6749 error format, which OAuth client
+// libraries expect instead of the usual message object.
+func writeOAuthError(w http.ResponseWriter, err error) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		res.ResJson(w, &payload.OAuthErrorResponse{Error: "server_error", ErrorDescription: err.Error()}, http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusBadRequest
+	switch oauthErr.Code {
+	case "invalid_client":
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		status = http.StatusUnauthorized
+	case "insufficient_scope":
+		status = http.StatusForbidden
+	}
+
+	res.ResJson(w, &payload.OAuthErrorResponse{Error: oauthErr.Code, ErrorDescription: oauthErr.Description}, status)
+}
+
+func writeOAuthClientError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, consts.ErrOAuthClientNotFound):
+		res.ErrResJson(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, consts.ErrInvalidOAuthClient):
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+	default:
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+	}
+}
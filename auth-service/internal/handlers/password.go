@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"auth-service/internal/middleware"
+	"auth-service/internal/payload"
+	"auth-service/internal/service"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/req"
+	"auth-service/pkg/res"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type PasswordHandler struct {
+	PasswordService *service.PasswordService
+}
+
+func NewPasswordHandler(router *http.ServeMux, passwordService *service.PasswordService, authMiddleware *middleware.AuthMiddleware) {
+	handler := &PasswordHandler{
+		PasswordService: passwordService,
+	}
+
+	router.Handle("POST /password/change", authMiddleware.Authenticate(http.HandlerFunc(handler.ChangePassword)))
+	router.HandleFunc("POST /password/reset/request", handler.RequestReset)
+	router.HandleFunc("POST /password/reset/confirm", handler.ConfirmReset)
+}
+
+func (h *PasswordHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := req.DecodedAndValidatedBody[payload.ChangePasswordPayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	err = h.PasswordService.Change(ctx, claims.UserID, clientIP(r), &body)
+	if writeThrottled(w, err) {
+		return
+	}
+	if errors.Is(err, consts.ErrInvalidCredentials) || errors.Is(err, consts.ErrWeakPassword) {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := &payload.AuthMessageResponse{
+		Message: "Password was changed, please log in again",
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func (h *PasswordHandler) RequestReset(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := req.DecodedAndValidatedBody[payload.RequestPasswordResetPayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.PasswordService.RequestReset(ctx, &body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := &payload.AuthMessageResponse{
+		Message: "If the email is registered, a reset link has been sent",
+	}
+
+	res.ResJson(w, data, http.StatusAccepted)
+}
+
+func (h *PasswordHandler) ConfirmReset(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := req.DecodedAndValidatedBody[payload.ConfirmPasswordResetPayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.PasswordService.ConfirmReset(ctx, &body)
+	if errors.Is(err, consts.ErrInvalidResetToken) || errors.Is(err, consts.ErrWeakPassword) {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := &payload.AuthMessageResponse{
+		Message: "Password was reset, please log in",
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
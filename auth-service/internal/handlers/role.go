@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"auth-service/internal/middleware"
+	"auth-service/internal/service"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/res"
+	"context"
+	"net/http"
+	"time"
+)
+
+type RoleHandler struct {
+	RoleService *service.RoleService
+}
+
+func NewRoleHandler(router *http.ServeMux, roleService *service.RoleService, authMiddleware *middleware.AuthMiddleware) {
+	handler := &RoleHandler{
+		RoleService: roleService,
+	}
+
+	router.Handle("GET /admin/roles", authMiddleware.RequirePermission(consts.PermUsersManage, http.HandlerFunc(handler.ListRoles)))
+}
+
+func (h *RoleHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	data, err := h.RoleService.List(ctx)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
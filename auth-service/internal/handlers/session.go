@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"auth-service/internal/middleware"
+	"auth-service/internal/payload"
+	"auth-service/internal/service"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/res"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type SessionHandler struct {
+	SessionService *service.SessionService
+}
+
+func NewSessionHandler(router *http.ServeMux, sessionService *service.SessionService, authMiddleware *middleware.AuthMiddleware) {
+	handler := &SessionHandler{
+		SessionService: sessionService,
+	}
+
+	router.Handle("GET /sessions", authMiddleware.Authenticate(http.HandlerFunc(handler.ListSessions)))
+	router.Handle("DELETE /sessions/{id}", authMiddleware.Authenticate(http.HandlerFunc(handler.RevokeSession)))
+}
+
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	data, err := h.SessionService.List(ctx, claims.UserID, claims.SessionID)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	err := h.SessionService.Revoke(ctx, claims.UserID, r.PathValue("id"))
+	if errors.Is(err, consts.ErrSessionNotFound) {
+		res.ErrResJson(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res.ResJson(w, &payload.AuthMessageResponse{Message: "Session revoked"}, http.StatusOK)
+}
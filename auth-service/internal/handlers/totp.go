@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"auth-service/internal/middleware"
+	"auth-service/internal/payload"
+	"auth-service/internal/service"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/req"
+	"auth-service/pkg/res"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type TOTPHandler struct {
+	AuthService *service.AuthService
+	TOTPService *service.TOTPService
+}
+
+func NewTOTPHandler(
+	router *http.ServeMux,
+	authService *service.AuthService,
+	totpService *service.TOTPService,
+	authMiddleware *middleware.AuthMiddleware,
+) {
+	handler := &TOTPHandler{
+		AuthService: authService,
+		TOTPService: totpService,
+	}
+
+	router.HandleFunc("POST /login/totp", handler.LoginTOTP)
+	router.Handle("POST /2fa/totp/enroll", authMiddleware.Authenticate(http.HandlerFunc(handler.Enroll)))
+	router.Handle("POST /2fa/totp/confirm", authMiddleware.Authenticate(http.HandlerFunc(handler.Confirm)))
+	router.Handle("POST /2fa/totp/disable", authMiddleware.Authenticate(http.HandlerFunc(handler.Disable)))
+	router.Handle("DELETE /admin/users/{id}/totp", authMiddleware.RequirePermission(consts.PermUsersManage, http.HandlerFunc(handler.Reset)))
+}
+
+func (h *TOTPHandler) LoginTOTP(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := req.DecodedAndValidatedBody[payload.TOTPLoginPayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data, err := h.AuthService.LoginTOTP(ctx, &body, clientInfo(r))
+	if writeThrottled(w, err) {
+		return
+	}
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func (h *TOTPHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	data, err := h.TOTPService.Enroll(ctx, claims.UserID)
+	if err != nil {
+		writeTOTPError(w, err)
+		return
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func (h *TOTPHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := req.DecodedAndValidatedBody[payload.TOTPCodePayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	codes, err := h.TOTPService.Confirm(ctx, claims.UserID, body.Code)
+	if err != nil {
+		writeTOTPError(w, err)
+		return
+	}
+
+	data := &payload.TOTPConfirmResponse{
+		RecoveryCodes: codes,
+		Message:       "Two-factor authentication enabled, store the recovery codes safely",
+	}
+
+	res.ResJson(w, data, http.StatusOK)
+}
+
+func (h *TOTPHandler) Disable(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	body, err := req.DecodedAndValidatedBody[payload.TOTPCodePayload](r.Body)
+	if err != nil {
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims := middleware.ClaimsFromContext(ctx)
+
+	err = h.TOTPService.Disable(ctx, claims.UserID, body.Code)
+	if err != nil {
+		writeTOTPError(w, err)
+		return
+	}
+
+	res.ResJson(w, &payload.AuthMessageResponse{Message: "Two-factor authentication disabled"}, http.StatusOK)
+}
+
+func (h *TOTPHandler) Reset(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		res.ErrResJson(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	err = h.TOTPService.Reset(ctx, uint(id))
+	if err != nil {
+		writeTOTPError(w, err)
+		return
+	}
+
+	res.ResJson(w, &payload.AuthMessageResponse{Message: "Two-factor authentication reset"}, http.StatusOK)
+}
+
+func writeTOTPError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, consts.ErrInvalidTOTPCode),
+		errors.Is(err, consts.ErrTOTPNotEnrolled),
+		errors.Is(err, consts.ErrTOTPAlreadyEnabled):
+		res.ErrResJson(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, consts.ErrUserNotFound):
+		res.ErrResJson(w, err.Error(), http.StatusNotFound)
+	default:
+		res.ErrResJson(w, err.Error(), http.StatusInternalServerError)
+	}
+}
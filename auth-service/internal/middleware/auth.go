@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"auth-service/internal/service"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/jwt"
+	"auth-service/pkg/res"
+	"context"
+	"net/http"
+	"strings"
+)
+
+type key string
+
+const ClaimsKey key = "claims"
+
+type AuthMiddleware struct {
+	AuthService *service.AuthService
+}
+
+func NewAuthMiddleware(authService *service.AuthService) *AuthMiddleware {
+	return &AuthMiddleware{
+		AuthService: authService,
+	}
+}
+
+func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			res.ErrResJson(w, consts.ErrMissingAuthHeader.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := m.AuthService.Authenticate(r.Context(), strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			res.ErrResJson(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ClaimsKey, claims)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (m *AuthMiddleware) RequirePermission(permission string, next http.Handler) http.Handler {
+	return m.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := ClaimsFromContext(r.Context())
+		if claims == nil || !claims.HasPermission(permission) {
+			res.ErrResJson(w, consts.ErrAccessDenied.Error(), http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	}))
+}
+
+func ClaimsFromContext(ctx context.Context) *jwt.Claims {
+	claims, _ := ctx.Value(ClaimsKey).(*jwt.Claims)
+	return claims
+}
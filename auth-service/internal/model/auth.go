@@ -1,8 +1,16 @@
 package model
 
+import "time"
+
 type User struct {
-	ID           uint   `json:"id"`
-	UserName     string `json:"username"`
-	PasswordHash string `json:"password_hash"`
-	Role         string `json:"role"`
+	ID                  uint       `json:"id"`
+	UserName            string     `json:"username"`
+	Email               *string    `json:"email"`
+	PasswordHash        string     `json:"-"`
+	Role                string     `json:"role"`
+	ServiceAccount      bool       `json:"service_account"`
+	Permissions         []string   `json:"permissions,omitempty"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
 }
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+type Invitation struct {
+	ID        uint       `json:"id"`
+	CodeHash  string     `json:"-"`
+	Role      string     `json:"role"`
+	CreatedBy uint       `json:"created_by"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedBy    *uint      `json:"used_by"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
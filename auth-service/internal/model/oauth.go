@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// OAuthClient is a third-party application registered to obtain tokens
+// through the OAuth2 endpoints. Public clients (SPAs, native apps) have no
+// secret and must rely on PKCE alone.
+type OAuthClient struct {
+	ID           string     `json:"client_id"`
+	SecretHash   *string    `json:"-"`
+	Name         string     `json:"name"`
+	RedirectURIs []string   `json:"redirect_uris"`
+	Scopes       []string   `json:"scopes"`
+	GrantTypes   []string   `json:"grant_types"`
+	Public       bool       `json:"public"`
+	CreatedBy    *uint      `json:"created_by,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+type OAuthAuthorizationCode struct {
+	ID            uint
+	CodeHash      string
+	ClientID      string
+	UserID        uint
+	RedirectURI   string
+	Scopes        []string
+	CodeChallenge string
+	SessionID     *string
+	ExpiresAt     time.Time
+	UsedAt        *time.Time
+}
+
+// OAuthConsent is the set of scopes a user approved for a client.
+type OAuthConsent struct {
+	ClientID   string    `json:"client_id"`
+	ClientName string    `json:"client_name"`
+	Scopes     []string  `json:"scopes"`
+	GrantedAt  time.Time `json:"granted_at"`
+}
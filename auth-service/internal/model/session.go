@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// Session is one login of a user, or one grant the user gave to an OAuth
+// client (ClientID set). Its ID is also the family ID of the session's refresh
+// tokens and the sid claim of its access tokens.
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     uint       `json:"user_id"`
+	ClientID   *string    `json:"client_id,omitempty"`
+	Scopes     []string   `json:"scopes,omitempty"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Current    bool       `json:"current"`
+}
+
+// ClientInfo describes the client that makes a login or refresh request.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
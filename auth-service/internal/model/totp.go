@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+type UserTOTP struct {
+	UserID       uint       `json:"user_id"`
+	Secret       string     `json:"-"`
+	EnabledAt    *time.Time `json:"enabled_at"`
+	LastUsedStep int64      `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type MFAChallenge struct {
+	ID        uint      `json:"id"`
+	UserID    uint      `json:"user_id"`
+	TokenHash string    `json:"-"`
+	Attempts  int       `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,28 @@
+package payload
+
+import (
+	"auth-service/internal/model"
+	"time"
+)
+
+type DeleteAccountPayload struct {
+	Password string `json:"password" validate:"required,max=256"`
+}
+
+type DeleteAccountResponse struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+	Message             string    `json:"message"`
+}
+
+// AccountExport is the archive of everything auth-service stores about a
+// user. Secrets (password hash, key and token hashes, TOTP secret) are left
+// out on purpose.
+type AccountExport struct {
+	ExportedAt    time.Time            `json:"exported_at"`
+	User          model.User           `json:"user"`
+	TOTPEnabled   bool                 `json:"totp_enabled"`
+	Sessions      []model.Session      `json:"sessions"`
+	APIKeys       []model.APIKey       `json:"api_keys"`
+	OAuthConsents []model.OAuthConsent `json:"oauth_consents"`
+	Invitations   []model.Invitation   `json:"invitations"`
+}
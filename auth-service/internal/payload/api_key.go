@@ -0,0 +1,19 @@
+package payload
+
+import "auth-service/internal/model"
+
+type CreateAPIKeyPayload struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=read write"`
+	ExpiresInDays *int     `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+type CreateAPIKeyResponse struct {
+	model.APIKey
+	Key string `json:"key"`
+}
+
+type CreateServiceAccountPayload struct {
+	Name string `json:"name" validate:"required,max=50"`
+	Role string `json:"role" validate:"omitempty,max=20"`
+}
@@ -1,14 +1,18 @@
 package payload
 
 type AuthRegisterPayload struct {
-	Username string `json:"username" validate:"required,min=1,max=50"`
-	Password string `json:"password" validate:"required,min=1,max=12"`
-	Role     string `json:"role" validate:"required,max=20"`
+	Username string  `json:"username" validate:"required,min=1,max=50"`
+	Email    *string `json:"email" validate:"omitempty,email,max=255"`
+	Password string  `json:"password" validate:"required"`
 }
 
 type AuthLoginPayload struct {
 	Username string `json:"username" validate:"required,min=1,max=50"`
-	Password string `json:"password" validate:"required,min=1,max=12"`
+	Password string `json:"password" validate:"required,max=256"`
+}
+
+type AuthLogoutPayload struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 type AuthRegisterResponse struct {
@@ -16,6 +20,18 @@ type AuthRegisterResponse struct {
 	Message string `json:"message"`
 }
 
+type AuthRefreshPayload struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
 type AuthLoginResponse struct {
-	Token string `json:"token"`
+	Token          string `json:"token,omitempty"`
+	RefreshToken   string `json:"refresh_token,omitempty"`
+	ExpiresIn      int64  `json:"expires_in,omitempty"`
+	MFARequired    bool   `json:"mfa_required,omitempty"`
+	ChallengeToken string `json:"challenge_token,omitempty"`
+}
+
+type AuthMessageResponse struct {
+	Message string `json:"message"`
 }
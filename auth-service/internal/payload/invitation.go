@@ -0,0 +1,22 @@
+package payload
+
+import "time"
+
+type CreateInvitationPayload struct {
+	Role string `json:"role" validate:"omitempty,max=20"`
+}
+
+type RedeemInvitationPayload struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type CreateInvitationResponse struct {
+	Code      string    `json:"code"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type RedeemInvitationResponse struct {
+	Role    string `json:"role"`
+	Message string `json:"message"`
+}
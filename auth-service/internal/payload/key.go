@@ -0,0 +1,9 @@
+package payload
+
+import "time"
+
+type RotateKeyResponse struct {
+	KID         string    `json:"kid"`
+	ActivatesAt time.Time `json:"activates_at"`
+	Message     string    `json:"message"`
+}
@@ -0,0 +1,90 @@
+package payload
+
+import "auth-service/internal/model"
+
+type CreateOAuthClientPayload struct {
+	Name         string   `json:"name" validate:"required,min=1,max=100"`
+	RedirectURIs []string `json:"redirect_uris" validate:"max=10,dive,url,max=2000"`
+	Scopes       []string `json:"scopes" validate:"required,min=1"`
+	GrantTypes   []string `json:"grant_types" validate:"required,min=1,dive,oneof=authorization_code client_credentials refresh_token"`
+	Public       bool     `json:"public"`
+}
+
+type CreateOAuthClientResponse struct {
+	model.OAuthClient
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// AuthorizeRequest holds the query parameters of /oauth/authorize.
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+type AuthorizeDecisionPayload struct {
+	Approve bool `json:"approve"`
+}
+
+type AuthorizeClient struct {
+	ID   string `json:"client_id"`
+	Name string `json:"name"`
+}
+
+// AuthorizeResponse describes a pending authorization to the user's front
+// end. Once consent is given RedirectTo holds the client callback with the
+// code (or the error) that the front end must navigate to.
+type AuthorizeResponse struct {
+	Client          AuthorizeClient `json:"client"`
+	Scopes          []string        `json:"scopes"`
+	ConsentRequired bool            `json:"consent_required"`
+	RedirectTo      string          `json:"redirect_to,omitempty"`
+}
+
+// TokenRequest holds the form parameters of /oauth/token.
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string
+	ClientID     string
+	ClientSecret string
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// IntrospectionResponse follows RFC 7662. Role, permissions and sid are
+// additions of this service, revoked tells a revoked token from an expired or
+// malformed one.
+type IntrospectionResponse struct {
+	Active      bool     `json:"active"`
+	Revoked     bool     `json:"revoked,omitempty"`
+	Subject     string   `json:"sub,omitempty"`
+	Username    string   `json:"username,omitempty"`
+	Role        string   `json:"role,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	ClientID    string   `json:"client_id,omitempty"`
+	SessionID   string   `json:"sid,omitempty"`
+	TokenType   string   `json:"token_type,omitempty"`
+	ExpiresAt   int64    `json:"exp,omitempty"`
+	IssuedAt    int64    `json:"iat,omitempty"`
+	JTI         string   `json:"jti,omitempty"`
+}
@@ -0,0 +1,15 @@
+package payload
+
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=256"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type RequestPasswordResetPayload struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+type ConfirmPasswordResetPayload struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
@@ -0,0 +1,20 @@
+package payload
+
+type TOTPCodePayload struct {
+	Code string `json:"code" validate:"required,max=32"`
+}
+
+type TOTPLoginPayload struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required,max=32"`
+}
+
+type TOTPEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type TOTPConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+	Message       string   `json:"message"`
+}
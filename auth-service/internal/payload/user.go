@@ -0,0 +1,19 @@
+package payload
+
+import "auth-service/internal/model"
+
+type UpdateUserRolePayload struct {
+	Role string `json:"role" validate:"required,max=20"`
+}
+
+type ListUsersResponse struct {
+	Data  []model.User `json:"data"`
+	Page  uint64       `json:"page"`
+	Limit uint64       `json:"limit"`
+	Total uint         `json:"total"`
+}
+
+type MeResponse struct {
+	model.User
+	TOTPEnabled bool `json:"totp_enabled"`
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/postgres"
+	"auth-service/pkg/consts"
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+type AccountRepository struct {
+	Database *postgres.Db
+}
+
+func NewAccountRepository(db *postgres.Db) *AccountRepository {
+	return &AccountRepository{
+		Database: db,
+	}
+}
+
+// ScheduleDeletion marks the user for deletion at the given time. A deletion
+// that is already scheduled keeps its original date. The last active admin
+// cannot schedule their deletion, it fails with consts.ErrLastAdmin.
+func (r *AccountRepository) ScheduleDeletion(ctx context.Context, userID uint, at time.Time) (time.Time, error) {
+	query, args, err := sq.
+		Update("users").
+		Set("deletion_scheduled_at", sq.Expr("COALESCE(deletion_scheduled_at, ?)", at)).
+		Where(sq.Eq{"id": userID}).
+		Suffix("RETURNING deletion_scheduled_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return time.Time{}, consts.ErrFailedToBuildSQL
+	}
+
+	tx, err := r.Database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return time.Time{}, consts.ErrFailedToBeginTx
+	}
+
+	defer tx.Rollback()
+
+	err = guardLastAdmin(ctx, tx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var scheduledAt time.Time
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&scheduledAt)
+	if err != nil {
+		return time.Time{}, consts.ErrFailedScheduleDeletion
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return time.Time{}, consts.ErrFailedToCommitTx
+	}
+
+	return scheduledAt, nil
+}
+
+func (r *AccountRepository) CancelDeletion(ctx context.Context, userID uint) error {
+	query, args, err := sq.
+		Update("users").
+		Set("deletion_scheduled_at", nil).
+		Where(sq.Eq{"id": userID}).
+		Where(sq.NotEq{"deletion_scheduled_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	result, err := r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedScheduleDeletion
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return consts.ErrFailedScheduleDeletion
+	}
+
+	if rows == 0 {
+		return consts.ErrDeletionNotScheduled
+	}
+
+	return nil
+}
+
+// ListDueDeletions returns the users whose cooling-off period is over.
+func (r *AccountRepository) ListDueDeletions(ctx context.Context) ([]model.User, error) {
+	query, args, err := sq.
+		Select("id", "username").
+		From("users").
+		Where(sq.Expr("deletion_scheduled_at <= NOW()")).
+		OrderBy("deletion_scheduled_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	rows, err := r.Database.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, consts.ErrFailedPurgeAccounts
+	}
+
+	defer rows.Close()
+
+	users := []model.User{}
+
+	for rows.Next() {
+		var user model.User
+		err := rows.Scan(&user.ID, &user.UserName)
+		if err != nil {
+			return nil, consts.ErrFailedPurgeAccounts
+		}
+		users = append(users, user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, consts.ErrFailedPurgeAccounts
+	}
+
+	return users, nil
+}
+
+// ListSessions returns every session of the user, including ended ones.
+func (r *AccountRepository) ListSessions(ctx context.Context, userID uint) ([]model.Session, error) {
+	query, args, err := sq.
+		Select("id", "user_id", "client_id", "scopes", "user_agent", "ip", "created_at", "last_seen_at", "expires_at", "revoked_at").
+		From("sessions").
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	rows, err := r.Database.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, consts.ErrFailedExportAccount
+	}
+
+	defer rows.Close()
+
+	sessions := []model.Session{}
+
+	for rows.Next() {
+		var s model.Session
+		err := rows.Scan(
+			&s.ID,
+			&s.UserID,
+			&s.ClientID,
+			pq.Array(&s.Scopes),
+			&s.UserAgent,
+			&s.IP,
+			&s.CreatedAt,
+			&s.LastSeenAt,
+			&s.ExpiresAt,
+			&s.RevokedAt,
+		)
+		if err != nil {
+			return nil, consts.ErrFailedExportAccount
+		}
+		sessions = append(sessions, s)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, consts.ErrFailedExportAccount
+	}
+
+	return sessions, nil
+}
+
+func (r *AccountRepository) ListConsents(ctx context.Context, userID uint) ([]model.OAuthConsent, error) {
+	query, args, err := sq.
+		Select("c.client_id", "o.name", "c.scopes", "c.granted_at").
+		From("oauth_consents c").
+		Join("oauth_clients o ON o.id = c.client_id").
+		Where(sq.Eq{"c.user_id": userID}).
+		OrderBy("c.granted_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	rows, err := r.Database.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, consts.ErrFailedExportAccount
+	}
+
+	defer rows.Close()
+
+	consents := []model.OAuthConsent{}
+
+	for rows.Next() {
+		var c model.OAuthConsent
+		err := rows.Scan(&c.ClientID, &c.ClientName, pq.Array(&c.Scopes), &c.GrantedAt)
+		if err != nil {
+			return nil, consts.ErrFailedExportAccount
+		}
+		consents = append(consents, c)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, consts.ErrFailedExportAccount
+	}
+
+	return consents, nil
+}
+
+// ListInvitations returns the invitations the user created or redeemed.
+func (r *AccountRepository) ListInvitations(ctx context.Context, userID uint) ([]model.Invitation, error) {
+	query, args, err := sq.
+		Select("id", "role", "COALESCE(created_by, 0)", "expires_at", "used_by", "used_at", "created_at").
+		From("invitations").
+		Where(sq.Or{sq.Eq{"created_by": userID}, sq.Eq{"used_by": userID}}).
+		OrderBy("created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	rows, err := r.Database.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, consts.ErrFailedExportAccount
+	}
+
+	defer rows.Close()
+
+	invitations := []model.Invitation{}
+
+	for rows.Next() {
+		var i model.Invitation
+		err := rows.Scan(&i.ID, &i.Role, &i.CreatedBy, &i.ExpiresAt, &i.UsedBy, &i.UsedAt, &i.CreatedAt)
+		if err != nil {
+			return nil, consts.ErrFailedExportAccount
+		}
+		invitations = append(invitations, i)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, consts.ErrFailedExportAccount
+	}
+
+	return invitations, nil
+}
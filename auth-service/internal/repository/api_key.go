@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/postgres"
+	"auth-service/pkg/consts"
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+type APIKeyRepository struct {
+	Database *postgres.Db
+}
+
+func NewAPIKeyRepository(db *postgres.Db) *APIKeyRepository {
+	return &APIKeyRepository{
+		Database: db,
+	}
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, k *model.APIKey) error {
+	query, args, err := sq.
+		Insert("api_keys").
+		Columns("user_id", "name", "prefix", "key_hash", "scopes", "expires_at").
+		Values(k.UserID, k.Name, k.Prefix, k.KeyHash, pq.Array(k.Scopes), k.ExpiresAt).
+		Suffix("RETURNING id, created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return consts.ErrFailedCreateAPIKey
+	}
+
+	return nil
+}
+
+func (r *APIKeyRepository) ListByUser(ctx context.Context, userID uint) ([]model.APIKey, error) {
+	query, args, err := sq.
+		Select("id", "user_id", "name", "prefix", "scopes", "expires_at", "revoked_at", "created_at").
+		From("api_keys").
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	rows, err := r.Database.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, consts.ErrFailedListAPIKeys
+	}
+
+	defer rows.Close()
+
+	keys := []model.APIKey{}
+
+	for rows.Next() {
+		var k model.APIKey
+		err := rows.Scan(
+			&k.ID,
+			&k.UserID,
+			&k.Name,
+			&k.Prefix,
+			pq.Array(&k.Scopes),
+			&k.ExpiresAt,
+			&k.RevokedAt,
+			&k.CreatedAt,
+		)
+		if err != nil {
+			return nil, consts.ErrFailedListAPIKeys
+		}
+		keys = append(keys, k)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, consts.ErrFailedListAPIKeys
+	}
+
+	return keys, nil
+}
+
+// Revoke marks the key as revoked. The key must belong to userID, so users
+// cannot revoke each other's keys by guessing IDs.
+func (r *APIKeyRepository) Revoke(ctx context.Context, userID uint, id uint) error {
+	query, args, err := sq.
+		Update("api_keys").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": id}).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	result, err := r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedRevokeAPIKey
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return consts.ErrFailedRevokeAPIKey
+	}
+
+	if rows == 0 {
+		return consts.ErrAPIKeyNotFound
+	}
+
+	return nil
+}
@@ -6,8 +6,11 @@ import (
 	"auth-service/internal/postgres"
 	"auth-service/pkg/consts"
 	"context"
+	"database/sql"
+	"strings"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
 )
 
 type AuthRepository struct {
@@ -20,11 +23,11 @@ func NewAuthRepository(db *postgres.Db) *AuthRepository {
 	}
 }
 
-func (r *AuthRepository) Register(ctx context.Context, p *payload.AuthRegisterPayload) (uint, error) {
+func (r *AuthRepository) Register(ctx context.Context, p *payload.AuthRegisterPayload, role string) (uint, error) {
 	query, args, err := sq.
 		Insert("users").
-		Columns("username", "password_hash", "role").
-		Values(p.Username, p.Password, p.Role).
+		Columns("username", "email", "password_hash", "role").
+		Values(p.Username, p.Email, p.Password, role).
 		Suffix("RETURNING id").
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
@@ -43,12 +46,87 @@ func (r *AuthRepository) Register(ctx context.Context, p *payload.AuthRegisterPa
 	return userID, nil
 }
 
+// CreateServiceAccount adds a user that can only authenticate with API keys.
+// Its empty password hash never matches, so password login is impossible.
+func (r *AuthRepository) CreateServiceAccount(ctx context.Context, name string, role string) (uint, error) {
+	query, args, err := sq.
+		Insert("users").
+		Columns("username", "password_hash", "role", "service_account").
+		Values(name, "", role, true).
+		Suffix("RETURNING id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, consts.ErrFailedToBuildSQL
+	}
+
+	var userID uint
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(&userID)
+	if err != nil {
+		return 0, consts.ErrFailedCreateUser
+	}
+
+	return userID, nil
+}
+
 func (r *AuthRepository) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
+	return r.getUser(ctx, sq.Eq{"username": username}, consts.ErrFailedGetUserByUserName)
+}
+
+func (r *AuthRepository) GetUserByID(ctx context.Context, id uint) (*model.User, error) {
+	return r.getUser(ctx, sq.Eq{"id": id}, consts.ErrFailedGetUserByID)
+}
+
+func (r *AuthRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	return r.getUser(ctx, sq.Eq{"LOWER(email)": strings.ToLower(email)}, consts.ErrFailedGetUserByEmail)
+}
+
+func (r *AuthRepository) UpdatePassword(ctx context.Context, id uint, passwordHash string) error {
+	query, args, err := sq.
+		Update("users").
+		Set("password_hash", passwordHash).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	result, err := r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedUpdatePassword
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return consts.ErrFailedUpdatePassword
+	}
+
+	if rows == 0 {
+		return consts.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (r *AuthRepository) getUser(ctx context.Context, where sq.Eq, failure error) (*model.User, error) {
 	var user model.User
 	query, args, err := sq.
-		Select("id", "username", "password_hash", "role").
+		Select(
+			"id",
+			"username",
+			"email",
+			"password_hash",
+			"role",
+			"service_account",
+			"disabled_at",
+			"deletion_scheduled_at",
+			"created_at",
+			"ARRAY(SELECT permission FROM role_permissions WHERE role_permissions.role = users.role ORDER BY permission)",
+		).
 		From("users").
-		Where(sq.Eq{"username": username}).
+		Where(where).
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 	if err != nil {
@@ -58,13 +136,42 @@ func (r *AuthRepository) GetUserByUsername(ctx context.Context, username string)
 	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(
 		&user.ID,
 		&user.UserName,
+		&user.Email,
 		&user.PasswordHash,
 		&user.Role,
+		&user.ServiceAccount,
+		&user.DisabledAt,
+		&user.DeletionScheduledAt,
+		&user.CreatedAt,
+		pq.Array(&user.Permissions),
 	)
+	if err == sql.ErrNoRows {
+		return nil, consts.ErrUserNotFound
+	}
 	if err != nil {
-		return nil, consts.ErrFailedGetUserByUserName
+		return nil, failure
 	}
 
 	return &user, nil
+}
+
+func (r *AuthRepository) HasAdmin(ctx context.Context) (bool, error) {
+	query, args, err := sq.
+		Select("COUNT(*) > 0").
+		From("users").
+		Where(sq.Eq{"role": consts.RoleAdmin}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, consts.ErrFailedToBuildSQL
+	}
+
+	var exists bool
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(&exists)
+	if err != nil {
+		return false, consts.ErrFailedCheckAdmin
+	}
 
+	return exists, nil
 }
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"auth-service/internal/postgres"
+	"auth-service/pkg/consts"
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+// ExpiringTables are the tables whose rows are useless once expires_at has
+// passed. Deleting a session cascades to its refresh tokens.
+var ExpiringTables = []string{
+	"sessions",
+	"refresh_tokens",
+	"revoked_tokens",
+	"mfa_challenges",
+	"password_reset_tokens",
+	"oauth_authorization_codes",
+}
+
+type CleanupRepository struct {
+	Database *postgres.Db
+}
+
+func NewCleanupRepository(db *postgres.Db) *CleanupRepository {
+	return &CleanupRepository{
+		Database: db,
+	}
+}
+
+// DeleteExpired removes the rows of table that expired and returns how many
+// were removed.
+func (r *CleanupRepository) DeleteExpired(ctx context.Context, table string) (int64, error) {
+	query, args, err := sq.
+		Delete(table).
+		Where(sq.Expr("expires_at < NOW()")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, consts.ErrFailedToBuildSQL
+	}
+
+	result, err := r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, consts.ErrFailedDeleteExpired
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, consts.ErrFailedDeleteExpired
+	}
+
+	return rows, nil
+}
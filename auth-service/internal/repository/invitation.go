@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/postgres"
+	"auth-service/pkg/consts"
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type InvitationRepository struct {
+	Database *postgres.Db
+}
+
+func NewInvitationRepository(db *postgres.Db) *InvitationRepository {
+	return &InvitationRepository{
+		Database: db,
+	}
+}
+
+func (r *InvitationRepository) Create(ctx context.Context, i *model.Invitation) error {
+	query, args, err := sq.
+		Insert("invitations").
+		Columns("code_hash", "role", "created_by", "expires_at").
+		Values(i.CodeHash, i.Role, i.CreatedBy, i.ExpiresAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedCreateInvitation
+	}
+
+	return nil
+}
+
+// Redeem consumes an unused, unexpired invitation and grants its role to the
+// user in a single transaction, so a code can never be used twice.
+func (r *InvitationRepository) Redeem(ctx context.Context, codeHash string, userID uint) (string, error) {
+	tx, err := r.Database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", consts.ErrFailedToBeginTx
+	}
+
+	defer tx.Rollback()
+
+	query, args, err := sq.
+		Select("id", "role").
+		From("invitations").
+		Where(sq.Eq{"code_hash": codeHash}).
+		Where(sq.Eq{"used_at": nil}).
+		Where(sq.Expr("expires_at > NOW()")).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return "", consts.ErrFailedToBuildSQL
+	}
+
+	var invitation model.Invitation
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&invitation.ID, &invitation.Role)
+	if err == sql.ErrNoRows {
+		return "", consts.ErrInvitationInvalid
+	}
+	if err != nil {
+		return "", consts.ErrFailedRedeemInvitation
+	}
+
+	query, args, err = sq.
+		Update("invitations").
+		Set("used_by", userID).
+		Set("used_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": invitation.ID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return "", consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return "", consts.ErrFailedRedeemInvitation
+	}
+
+	query, args, err = sq.
+		Update("users").
+		Set("role", invitation.Role).
+		Where(sq.Eq{"id": userID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return "", consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return "", consts.ErrFailedRedeemInvitation
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return "", consts.ErrFailedToCommitTx
+	}
+
+	return invitation.Role, nil
+}
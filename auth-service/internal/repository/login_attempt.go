@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/postgres"
+	"auth-service/pkg/consts"
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type LoginAttemptRepository struct {
+	Database *postgres.Db
+}
+
+func NewLoginAttemptRepository(db *postgres.Db) *LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		Database: db,
+	}
+}
+
+func (r *LoginAttemptRepository) Get(ctx context.Context, key string) (*model.LoginAttempt, error) {
+	query, args, err := sq.
+		Select("key", "failures", "last_failure_at", "locked_until").
+		From("login_attempts").
+		Where(sq.Eq{"key": key}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	var attempt model.LoginAttempt
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(
+		&attempt.Key,
+		&attempt.Failures,
+		&attempt.LastFailureAt,
+		&attempt.LockedUntil,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, consts.ErrFailedTrackAttempts
+	}
+
+	return &attempt, nil
+}
+
+// RecordFailure increments the failure counter for key, starting over when
+// the previous failure happened before windowStart.
+func (r *LoginAttemptRepository) RecordFailure(ctx context.Context, key string, windowStart time.Time) (*model.LoginAttempt, error) {
+	query, args, err := sq.
+		Insert("login_attempts").
+		Columns("key", "failures", "last_failure_at").
+		Values(key, 1, sq.Expr("NOW()")).
+		Suffix(`ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END,
+			locked_until = CASE WHEN login_attempts.last_failure_at < ? THEN NULL ELSE login_attempts.locked_until END,
+			last_failure_at = NOW()
+			RETURNING key, failures, last_failure_at, locked_until`, windowStart, windowStart).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	var attempt model.LoginAttempt
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(
+		&attempt.Key,
+		&attempt.Failures,
+		&attempt.LastFailureAt,
+		&attempt.LockedUntil,
+	)
+	if err != nil {
+		return nil, consts.ErrFailedTrackAttempts
+	}
+
+	return &attempt, nil
+}
+
+func (r *LoginAttemptRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	query, args, err := sq.
+		Update("login_attempts").
+		Set("locked_until", until).
+		Where(sq.Eq{"key": key}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedTrackAttempts
+	}
+
+	return nil
+}
+
+func (r *LoginAttemptRepository) Reset(ctx context.Context, key string) error {
+	query, args, err := sq.
+		Delete("login_attempts").
+		Where(sq.Eq{"key": key}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedTrackAttempts
+	}
+
+	return nil
+}
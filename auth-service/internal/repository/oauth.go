@@ -0,0 +1,359 @@
+package repository
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/postgres"
+	"auth-service/pkg/consts"
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+type OAuthRepository struct {
+	Database *postgres.Db
+}
+
+func NewOAuthRepository(db *postgres.Db) *OAuthRepository {
+	return &OAuthRepository{
+		Database: db,
+	}
+}
+
+func (r *OAuthRepository) CreateClient(ctx context.Context, c *model.OAuthClient) error {
+	query, args, err := sq.
+		Insert("oauth_clients").
+		Columns("id", "secret_hash", "name", "redirect_uris", "scopes", "grant_types", "created_by").
+		Values(c.ID, c.SecretHash, c.Name, pq.Array(c.RedirectURIs), pq.Array(c.Scopes), pq.Array(c.GrantTypes), c.CreatedBy).
+		Suffix("RETURNING created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(&c.CreatedAt)
+	if err != nil {
+		return consts.ErrFailedCreateOAuthClient
+	}
+
+	return nil
+}
+
+var oauthClientColumns = []string{
+	"id", "secret_hash", "name", "redirect_uris", "scopes", "grant_types", "created_by", "created_at", "revoked_at",
+}
+
+func scanOAuthClient(row interface{ Scan(...any) error }, c *model.OAuthClient) error {
+	err := row.Scan(
+		&c.ID,
+		&c.SecretHash,
+		&c.Name,
+		pq.Array(&c.RedirectURIs),
+		pq.Array(&c.Scopes),
+		pq.Array(&c.GrantTypes),
+		&c.CreatedBy,
+		&c.CreatedAt,
+		&c.RevokedAt,
+	)
+	c.Public = c.SecretHash == nil
+	return err
+}
+
+// GetClient returns an active client. Revoked clients are reported as not
+// found, so no flow can continue with them.
+func (r *OAuthRepository) GetClient(ctx context.Context, id string) (*model.OAuthClient, error) {
+	query, args, err := sq.
+		Select(oauthClientColumns...).
+		From("oauth_clients").
+		Where(sq.Eq{"id": id}).
+		Where(sq.Eq{"revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	var c model.OAuthClient
+
+	err = scanOAuthClient(r.Database.DB.QueryRowContext(ctx, query, args...), &c)
+	if err == sql.ErrNoRows {
+		return nil, consts.ErrOAuthClientNotFound
+	}
+	if err != nil {
+		return nil, consts.ErrFailedGetOAuthClient
+	}
+
+	return &c, nil
+}
+
+func (r *OAuthRepository) ListClients(ctx context.Context) ([]model.OAuthClient, error) {
+	query, args, err := sq.
+		Select(oauthClientColumns...).
+		From("oauth_clients").
+		OrderBy("created_at").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	rows, err := r.Database.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, consts.ErrFailedListOAuthClients
+	}
+
+	defer rows.Close()
+
+	clients := []model.OAuthClient{}
+
+	for rows.Next() {
+		var c model.OAuthClient
+		err := scanOAuthClient(rows, &c)
+		if err != nil {
+			return nil, consts.ErrFailedListOAuthClients
+		}
+		clients = append(clients, c)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, consts.ErrFailedListOAuthClients
+	}
+
+	return clients, nil
+}
+
+// RevokeClient disables a client and ends every session users granted it,
+// including their refresh tokens.
+func (r *OAuthRepository) RevokeClient(ctx context.Context, id string) error {
+	tx, err := r.Database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return consts.ErrFailedToBeginTx
+	}
+
+	defer tx.Rollback()
+
+	query, args, err := sq.
+		Update("oauth_clients").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": id}).
+		Where(sq.Eq{"revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedRevokeOAuthClient
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return consts.ErrFailedRevokeOAuthClient
+	}
+
+	if rows == 0 {
+		return consts.ErrOAuthClientNotFound
+	}
+
+	sessions := sq.Select("id").From("sessions").Where(sq.Eq{"client_id": id})
+
+	query, args, err = sq.
+		Update("refresh_tokens").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Expr("family_id IN (?)", sessions)).
+		Where(sq.Eq{"revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedRevokeOAuthClient
+	}
+
+	query, args, err = sq.
+		Update("sessions").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"client_id": id}).
+		Where(sq.Eq{"revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedRevokeOAuthClient
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return consts.ErrFailedToCommitTx
+	}
+
+	return nil
+}
+
+// GetConsent returns the scopes the user already approved for the client, or
+// nil if they never did.
+func (r *OAuthRepository) GetConsent(ctx context.Context, userID uint, clientID string) ([]string, error) {
+	query, args, err := sq.
+		Select("scopes").
+		From("oauth_consents").
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"client_id": clientID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	var scopes []string
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(pq.Array(&scopes))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, consts.ErrFailedOAuthConsent
+	}
+
+	return scopes, nil
+}
+
+func (r *OAuthRepository) SaveConsent(ctx context.Context, userID uint, clientID string, scopes []string) error {
+	query, args, err := sq.
+		Insert("oauth_consents").
+		Columns("user_id", "client_id", "scopes").
+		Values(userID, clientID, pq.Array(scopes)).
+		Suffix("ON CONFLICT (user_id, client_id) DO UPDATE SET scopes = EXCLUDED.scopes, granted_at = NOW()").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedOAuthConsent
+	}
+
+	return nil
+}
+
+func (r *OAuthRepository) CreateCode(ctx context.Context, c *model.OAuthAuthorizationCode) error {
+	query, args, err := sq.
+		Insert("oauth_authorization_codes").
+		Columns("code_hash", "client_id", "user_id", "redirect_uri", "scopes", "code_challenge", "expires_at").
+		Values(c.CodeHash, c.ClientID, c.UserID, c.RedirectURI, pq.Array(c.Scopes), c.CodeChallenge, c.ExpiresAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedOAuthCode
+	}
+
+	return nil
+}
+
+// ConsumeCode marks a code as used and returns it as it was before. A code
+// that was already used comes back with UsedAt set so the caller can treat
+// the replay, and nil is returned for an unknown code.
+func (r *OAuthRepository) ConsumeCode(ctx context.Context, codeHash string) (*model.OAuthAuthorizationCode, error) {
+	tx, err := r.Database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, consts.ErrFailedToBeginTx
+	}
+
+	defer tx.Rollback()
+
+	query, args, err := sq.
+		Select("id", "client_id", "user_id", "redirect_uri", "scopes", "code_challenge", "session_id", "expires_at", "used_at").
+		From("oauth_authorization_codes").
+		Where(sq.Eq{"code_hash": codeHash}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	c := model.OAuthAuthorizationCode{CodeHash: codeHash}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&c.ID,
+		&c.ClientID,
+		&c.UserID,
+		&c.RedirectURI,
+		pq.Array(&c.Scopes),
+		&c.CodeChallenge,
+		&c.SessionID,
+		&c.ExpiresAt,
+		&c.UsedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, consts.ErrFailedOAuthCode
+	}
+
+	if c.UsedAt != nil {
+		return &c, nil
+	}
+
+	query, args, err = sq.
+		Update("oauth_authorization_codes").
+		Set("used_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": c.ID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, consts.ErrFailedOAuthCode
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, consts.ErrFailedToCommitTx
+	}
+
+	return &c, nil
+}
+
+// SetCodeSession links a redeemed code to the session it started, so a
+// replay of the code can end that session.
+func (r *OAuthRepository) SetCodeSession(ctx context.Context, codeID uint, sessionID string) error {
+	query, args, err := sq.
+		Update("oauth_authorization_codes").
+		Set("session_id", sessionID).
+		Where(sq.Eq{"id": codeID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedOAuthCode
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"auth-service/internal/postgres"
+	"auth-service/pkg/consts"
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type PasswordResetRepository struct {
+	Database *postgres.Db
+}
+
+func NewPasswordResetRepository(db *postgres.Db) *PasswordResetRepository {
+	return &PasswordResetRepository{
+		Database: db,
+	}
+}
+
+func (r *PasswordResetRepository) Create(ctx context.Context, userID uint, tokenHash string, expiresAt time.Time) error {
+	query, args, err := sq.
+		Insert("password_reset_tokens").
+		Columns("user_id", "token_hash", "expires_at").
+		Values(userID, tokenHash, expiresAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedCreateReset
+	}
+
+	return nil
+}
+
+// Consume marks an unused, unexpired token as used and returns its user.
+// The check and the update are one statement, so a token works only once.
+func (r *PasswordResetRepository) Consume(ctx context.Context, tokenHash string) (uint, error) {
+	query, args, err := sq.
+		Update("password_reset_tokens").
+		Set("used_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"token_hash": tokenHash}).
+		Where(sq.Eq{"used_at": nil}).
+		Where(sq.Expr("expires_at > NOW()")).
+		Suffix("RETURNING user_id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, consts.ErrFailedToBuildSQL
+	}
+
+	var userID uint
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, consts.ErrInvalidResetToken
+	}
+	if err != nil {
+		return 0, consts.ErrFailedCreateReset
+	}
+
+	return userID, nil
+}
+
+func (r *PasswordResetRepository) InvalidateForUser(ctx context.Context, userID uint) error {
+	query, args, err := sq.
+		Update("password_reset_tokens").
+		Set("used_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"used_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedCreateReset
+	}
+
+	return nil
+}
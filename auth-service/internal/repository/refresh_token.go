@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/postgres"
+	"auth-service/pkg/consts"
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type RefreshTokenRepository struct {
+	Database *postgres.Db
+}
+
+func NewRefreshTokenRepository(db *postgres.Db) *RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		Database: db,
+	}
+}
+
+func (r *RefreshTokenRepository) Create(ctx context.Context, t *model.RefreshToken) error {
+	query, args, err := sq.
+		Insert("refresh_tokens").
+		Columns("user_id", "token_hash", "family_id", "expires_at").
+		Values(t.UserID, t.TokenHash, t.FamilyID, t.ExpiresAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedCreateRefresh
+	}
+
+	return nil
+}
+
+// Rotate marks the token identified by oldHash as used and stores newHash in
+// the same family. Presenting a token that was already used or revoked revokes
+// the whole family, so a stolen token stops working for both parties.
+func (r *RefreshTokenRepository) Rotate(ctx context.Context, oldHash string, newHash string, expiresAt time.Time) (*model.RefreshToken, error) {
+	tx, err := r.Database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, consts.ErrFailedToBeginTx
+	}
+
+	defer tx.Rollback()
+
+	query, args, err := sq.
+		Select("id", "user_id", "family_id", "expires_at", "used_at", "revoked_at").
+		From("refresh_tokens").
+		Where(sq.Eq{"token_hash": oldHash}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	var current model.RefreshToken
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&current.ID,
+		&current.UserID,
+		&current.FamilyID,
+		&current.ExpiresAt,
+		&current.UsedAt,
+		&current.RevokedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, consts.ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, consts.ErrFailedRotateRefresh
+	}
+
+	if current.UsedAt != nil || current.RevokedAt != nil {
+		err = revokeFamily(ctx, tx, current.FamilyID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return nil, consts.ErrFailedToCommitTx
+		}
+
+		return nil, consts.ErrRefreshTokenReused
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return nil, consts.ErrRefreshTokenExpired
+	}
+
+	query, args, err = sq.
+		Update("refresh_tokens").
+		Set("used_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": current.ID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, consts.ErrFailedRotateRefresh
+	}
+
+	next := &model.RefreshToken{
+		UserID:    current.UserID,
+		TokenHash: newHash,
+		FamilyID:  current.FamilyID,
+		ExpiresAt: expiresAt,
+	}
+
+	query, args, err = sq.
+		Insert("refresh_tokens").
+		Columns("user_id", "token_hash", "family_id", "expires_at").
+		Values(next.UserID, next.TokenHash, next.FamilyID, next.ExpiresAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, consts.ErrFailedCreateRefresh
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, consts.ErrFailedToCommitTx
+	}
+
+	return next, nil
+}
+
+func (r *RefreshTokenRepository) RevokeFamilyByHash(ctx context.Context, tokenHash string, userID uint) error {
+	familyQuery := sq.
+		Select("family_id").
+		From("refresh_tokens").
+		Where(sq.Eq{"token_hash": tokenHash}).
+		Where(sq.Eq{"user_id": userID})
+
+	query, args, err := sq.
+		Update("refresh_tokens").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Expr("family_id IN (?)", familyQuery)).
+		Where(sq.Eq{"revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedRevokeToken
+	}
+
+	query, args, err = sq.
+		Update("sessions").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Expr("id IN (?)", familyQuery)).
+		Where(sq.Eq{"revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedRevokeSession
+	}
+
+	return nil
+}
+
+// revokeFamily revokes all refresh tokens of a family and the session the
+// family belongs to.
+func revokeFamily(ctx context.Context, tx *sql.Tx, familyID string) error {
+	query, args, err := sq.
+		Update("refresh_tokens").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"family_id": familyID}).
+		Where(sq.Eq{"revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedRotateRefresh
+	}
+
+	query, args, err = sq.
+		Update("sessions").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": familyID}).
+		Where(sq.Eq{"revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedRevokeSession
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"auth-service/internal/postgres"
+	"auth-service/pkg/consts"
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type RevocationRepository struct {
+	Database *postgres.Db
+}
+
+func NewRevocationRepository(db *postgres.Db) *RevocationRepository {
+	return &RevocationRepository{
+		Database: db,
+	}
+}
+
+func (r *RevocationRepository) RevokeToken(ctx context.Context, jti string, userID uint, expiresAt time.Time) error {
+	query, args, err := sq.
+		Insert("revoked_tokens").
+		Columns("jti", "user_id", "expires_at").
+		Values(jti, userID, expiresAt).
+		Suffix("ON CONFLICT (jti) DO NOTHING").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedRevokeToken
+	}
+
+	return nil
+}
+
+// RevokeAllForUser invalidates every access token issued to the user up to now
+// and revokes all of the user's refresh tokens and sessions.
+func (r *RevocationRepository) RevokeAllForUser(ctx context.Context, userID uint) error {
+	tx, err := r.Database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return consts.ErrFailedToBeginTx
+	}
+
+	defer tx.Rollback()
+
+	query, args, err := sq.
+		Update("users").
+		Set("tokens_revoked_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": userID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedRevokeToken
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return consts.ErrFailedRevokeToken
+	}
+
+	if rows == 0 {
+		return consts.ErrUserNotFound
+	}
+
+	query, args, err = sq.
+		Update("refresh_tokens").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedRevokeToken
+	}
+
+	query, args, err = sq.
+		Update("sessions").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedRevokeSession
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return consts.ErrFailedToCommitTx
+	}
+
+	return nil
+}
+
+func (r *RevocationRepository) IsRevoked(ctx context.Context, jti string, userID uint, issuedAt time.Time) (bool, error) {
+	query, args, err := sq.
+		Select("1").
+		From("revoked_tokens").
+		Where(sq.Eq{"jti": jti}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, consts.ErrFailedToBuildSQL
+	}
+
+	var found int
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(&found)
+	if err == nil {
+		return true, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, consts.ErrFailedCheckRevocation
+	}
+
+	query, args, err = sq.
+		Select("tokens_revoked_at").
+		From("users").
+		Where(sq.Eq{"id": userID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, consts.ErrFailedToBuildSQL
+	}
+
+	var revokedAt sql.NullTime
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(&revokedAt)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	if err != nil {
+		return false, consts.ErrFailedCheckRevocation
+	}
+
+	return revokedAt.Valid && issuedAt.Unix() <= revokedAt.Time.Unix(), nil
+}
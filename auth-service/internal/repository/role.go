@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/postgres"
+	"auth-service/pkg/consts"
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+type RoleRepository struct {
+	Database *postgres.Db
+}
+
+func NewRoleRepository(db *postgres.Db) *RoleRepository {
+	return &RoleRepository{
+		Database: db,
+	}
+}
+
+func (r *RoleRepository) List(ctx context.Context) ([]model.Role, error) {
+	query, args, err := sq.
+		Select(
+			"name",
+			"description",
+			"ARRAY(SELECT permission FROM role_permissions WHERE role_permissions.role = roles.name ORDER BY permission)",
+		).
+		From("roles").
+		OrderBy("name").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	rows, err := r.Database.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, consts.ErrFailedListRoles
+	}
+
+	defer rows.Close()
+
+	roles := []model.Role{}
+
+	for rows.Next() {
+		var role model.Role
+		err := rows.Scan(&role.Name, &role.Description, pq.Array(&role.Permissions))
+		if err != nil {
+			return nil, consts.ErrFailedListRoles
+		}
+		roles = append(roles, role)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, consts.ErrFailedListRoles
+	}
+
+	return roles, nil
+}
+
+// Exists lets services reject unknown roles with a clear error instead of
+// relying on the foreign key violation.
+func (r *RoleRepository) Exists(ctx context.Context, name string) (bool, error) {
+	query, args, err := sq.
+		Select("COUNT(*) > 0").
+		From("roles").
+		Where(sq.Eq{"name": name}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, consts.ErrFailedToBuildSQL
+	}
+
+	var exists bool
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(&exists)
+	if err != nil {
+		return false, consts.ErrFailedListRoles
+	}
+
+	return exists, nil
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/postgres"
+	"auth-service/pkg/consts"
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+)
+
+type SessionRepository struct {
+	Database *postgres.Db
+}
+
+func NewSessionRepository(db *postgres.Db) *SessionRepository {
+	return &SessionRepository{
+		Database: db,
+	}
+}
+
+func (r *SessionRepository) Create(ctx context.Context, s *model.Session) error {
+	query, args, err := sq.
+		Insert("sessions").
+		Columns("id", "user_id", "client_id", "scopes", "user_agent", "ip", "expires_at").
+		Values(s.ID, s.UserID, s.ClientID, pq.Array(s.Scopes), s.UserAgent, s.IP, s.ExpiresAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedCreateSession
+	}
+
+	return nil
+}
+
+// Touch records that the session was used again, from client, and extends it
+// to expiresAt.
+func (r *SessionRepository) Touch(ctx context.Context, id string, client model.ClientInfo, expiresAt time.Time) error {
+	query, args, err := sq.
+		Update("sessions").
+		Set("last_seen_at", sq.Expr("NOW()")).
+		Set("ip", client.IP).
+		Set("user_agent", client.UserAgent).
+		Set("expires_at", expiresAt).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedUpdateSession
+	}
+
+	return nil
+}
+
+func (r *SessionRepository) ListActive(ctx context.Context, userID uint) ([]model.Session, error) {
+	query, args, err := sq.
+		Select("id", "user_id", "client_id", "scopes", "user_agent", "ip", "created_at", "last_seen_at", "expires_at").
+		From("sessions").
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"revoked_at": nil}).
+		Where(sq.Expr("expires_at > NOW()")).
+		OrderBy("last_seen_at DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	rows, err := r.Database.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, consts.ErrFailedListSessions
+	}
+
+	defer rows.Close()
+
+	sessions := []model.Session{}
+
+	for rows.Next() {
+		var s model.Session
+		err := rows.Scan(
+			&s.ID,
+			&s.UserID,
+			&s.ClientID,
+			pq.Array(&s.Scopes),
+			&s.UserAgent,
+			&s.IP,
+			&s.CreatedAt,
+			&s.LastSeenAt,
+			&s.ExpiresAt,
+		)
+		if err != nil {
+			return nil, consts.ErrFailedListSessions
+		}
+		sessions = append(sessions, s)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, consts.ErrFailedListSessions
+	}
+
+	return sessions, nil
+}
+
+// GetByRefreshToken returns the session a refresh token belongs to, whatever
+// the state of the token, so callers can check who may rotate it first.
+func (r *SessionRepository) GetByRefreshToken(ctx context.Context, tokenHash string) (*model.Session, error) {
+	query, args, err := sq.
+		Select("s.id", "s.user_id", "s.client_id", "s.scopes", "s.expires_at", "s.revoked_at").
+		From("sessions s").
+		Join("refresh_tokens t ON t.family_id = s.id").
+		Where(sq.Eq{"t.token_hash": tokenHash}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	var s model.Session
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(
+		&s.ID,
+		&s.UserID,
+		&s.ClientID,
+		pq.Array(&s.Scopes),
+		&s.ExpiresAt,
+		&s.RevokedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, consts.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, consts.ErrFailedListSessions
+	}
+
+	return &s, nil
+}
+
+// Revoke ends a session of userID together with its refresh tokens. Access
+// tokens of the session are rejected from then on through their sid claim.
+func (r *SessionRepository) Revoke(ctx context.Context, userID uint, id string) error {
+	tx, err := r.Database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return consts.ErrFailedToBeginTx
+	}
+
+	defer tx.Rollback()
+
+	query, args, err := sq.
+		Update("sessions").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"id": id}).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"revoked_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedRevokeSession
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return consts.ErrFailedRevokeSession
+	}
+
+	if rows == 0 {
+		return consts.ErrSessionNotFound
+	}
+
+	err = revokeFamily(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return consts.ErrFailedToCommitTx
+	}
+
+	return nil
+}
+
+// IsRevoked reports whether the session was revoked. A session that does not
+// exist, for example because its user was deleted, counts as revoked.
+func (r *SessionRepository) IsRevoked(ctx context.Context, id string) (bool, error) {
+	query, args, err := sq.
+		Select("revoked_at").
+		From("sessions").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, consts.ErrFailedToBuildSQL
+	}
+
+	var revokedAt sql.NullTime
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(&revokedAt)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	if err != nil {
+		return false, consts.ErrFailedCheckRevocation
+	}
+
+	return revokedAt.Valid, nil
+}
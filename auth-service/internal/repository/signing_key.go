@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/postgres"
+	"auth-service/pkg/consts"
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type SigningKeyRepository struct {
+	Database *postgres.Db
+}
+
+func NewSigningKeyRepository(db *postgres.Db) *SigningKeyRepository {
+	return &SigningKeyRepository{
+		Database: db,
+	}
+}
+
+// GetUsable returns keys that are either active or were retired less than
+// grace ago, newest first.
+func (r *SigningKeyRepository) GetUsable(ctx context.Context, grace time.Duration) ([]model.SigningKey, error) {
+	query, args, err := sq.
+		Select("kid", "algorithm", "private_key", "created_at", "activates_at", "retired_at").
+		From("signing_keys").
+		Where(sq.Or{
+			sq.Eq{"retired_at": nil},
+			sq.Gt{"retired_at": time.Now().Add(-grace)},
+		}).
+		OrderBy("created_at DESC").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	rows, err := r.Database.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, consts.ErrFailedLoadSigningKeys
+	}
+
+	defer rows.Close()
+
+	var keys []model.SigningKey
+
+	for rows.Next() {
+		var key model.SigningKey
+		err := rows.Scan(
+			&key.KID,
+			&key.Algorithm,
+			&key.PrivateKey,
+			&key.CreatedAt,
+			&key.ActivatesAt,
+			&key.RetiredAt,
+		)
+		if err != nil {
+			return nil, consts.ErrFailedLoadSigningKeys
+		}
+		keys = append(keys, key)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, consts.ErrFailedLoadSigningKeys
+	}
+
+	return keys, nil
+}
+
+// Rotate stores key and retires every active key at key.ActivatesAt, when key
+// takes over. Rotations are serialized with an advisory lock, so replicas
+// rotating at the same time do not retire each other's fresh keys. If
+// activatedAfter is set and a key activates later than it, a rotation already
+// happened elsewhere and Rotate reports false without changing anything.
+func (r *SigningKeyRepository) Rotate(ctx context.Context, key *model.SigningKey, activatedAfter time.Time) (bool, error) {
+	tx, err := r.Database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, consts.ErrFailedToBeginTx
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext('signing_keys'))")
+	if err != nil {
+		return false, consts.ErrFailedRotateSigningKey
+	}
+
+	if !activatedAfter.IsZero() {
+		query, args, err := sq.
+			Select("1").
+			From("signing_keys").
+			Where(sq.Gt{"activates_at": activatedAfter}).
+			Limit(1).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return false, consts.ErrFailedToBuildSQL
+		}
+
+		var found int
+
+		err = tx.QueryRowContext(ctx, query, args...).Scan(&found)
+		if err == nil {
+			return false, nil
+		}
+		if err != sql.ErrNoRows {
+			return false, consts.ErrFailedRotateSigningKey
+		}
+	}
+
+	query, args, err := sq.
+		Update("signing_keys").
+		Set("retired_at", key.ActivatesAt).
+		Where(sq.Eq{"retired_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, consts.ErrFailedRotateSigningKey
+	}
+
+	query, args, err = sq.
+		Insert("signing_keys").
+		Columns("kid", "algorithm", "private_key", "activates_at").
+		Values(key.KID, key.Algorithm, key.PrivateKey, key.ActivatesAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, consts.ErrFailedRotateSigningKey
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return false, consts.ErrFailedToCommitTx
+	}
+
+	return true, nil
+}
+
+// SetPrivateKey replaces the stored private key of kid, used to encrypt keys
+// stored in the clear.
+func (r *SigningKeyRepository) SetPrivateKey(ctx context.Context, kid string, privateKey string) error {
+	query, args, err := sq.
+		Update("signing_keys").
+		Set("private_key", privateKey).
+		Where(sq.Eq{"kid": kid}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedRotateSigningKey
+	}
+
+	return nil
+}
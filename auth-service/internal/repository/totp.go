@@ -0,0 +1,346 @@
+package repository
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/postgres"
+	"auth-service/pkg/consts"
+	"context"
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type TOTPRepository struct {
+	Database *postgres.Db
+}
+
+func NewTOTPRepository(db *postgres.Db) *TOTPRepository {
+	return &TOTPRepository{
+		Database: db,
+	}
+}
+
+func (r *TOTPRepository) GetByUserID(ctx context.Context, userID uint) (*model.UserTOTP, error) {
+	query, args, err := sq.
+		Select("user_id", "secret", "enabled_at", "last_used_step", "created_at").
+		From("user_totp").
+		Where(sq.Eq{"user_id": userID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	var t model.UserTOTP
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(
+		&t.UserID,
+		&t.Secret,
+		&t.EnabledAt,
+		&t.LastUsedStep,
+		&t.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, consts.ErrTOTPNotEnrolled
+	}
+	if err != nil {
+		return nil, consts.ErrFailedTOTP
+	}
+
+	return &t, nil
+}
+
+// SavePending stores a new, not yet confirmed secret, replacing any earlier
+// pending enrollment. An enabled secret is never overwritten.
+func (r *TOTPRepository) SavePending(ctx context.Context, userID uint, secret string) error {
+	query, args, err := sq.
+		Insert("user_totp").
+		Columns("user_id", "secret").
+		Values(userID, secret).
+		Suffix(`ON CONFLICT (user_id) DO UPDATE
+			SET secret = EXCLUDED.secret, last_used_step = 0, created_at = NOW()
+			WHERE user_totp.enabled_at IS NULL`).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	result, err := r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedTOTP
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return consts.ErrFailedTOTP
+	}
+
+	if rows == 0 {
+		return consts.ErrTOTPAlreadyEnabled
+	}
+
+	return nil
+}
+
+// Enable activates the pending secret and replaces the recovery codes.
+func (r *TOTPRepository) Enable(ctx context.Context, userID uint, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.Database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return consts.ErrFailedToBeginTx
+	}
+
+	defer tx.Rollback()
+
+	query, args, err := sq.
+		Update("user_totp").
+		Set("enabled_at", sq.Expr("NOW()")).
+		Set("last_used_step", step).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"enabled_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedTOTP
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return consts.ErrFailedTOTP
+	}
+
+	if rows == 0 {
+		return consts.ErrTOTPAlreadyEnabled
+	}
+
+	query, args, err = sq.
+		Delete("totp_recovery_codes").
+		Where(sq.Eq{"user_id": userID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedTOTP
+	}
+
+	insert := sq.Insert("totp_recovery_codes").Columns("user_id", "code_hash")
+	for _, hash := range recoveryCodeHashes {
+		insert = insert.Values(userID, hash)
+	}
+
+	query, args, err = insert.PlaceholderFormat(sq.Dollar).ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedTOTP
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return consts.ErrFailedToCommitTx
+	}
+
+	return nil
+}
+
+// UseStep records step as used. It returns false when the same or a later
+// step was already accepted, which means the code is being replayed.
+func (r *TOTPRepository) UseStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	query, args, err := sq.
+		Update("user_totp").
+		Set("last_used_step", step).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Lt{"last_used_step": step}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, consts.ErrFailedToBuildSQL
+	}
+
+	result, err := r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, consts.ErrFailedTOTP
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, consts.ErrFailedTOTP
+	}
+
+	return rows == 1, nil
+}
+
+func (r *TOTPRepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	query, args, err := sq.
+		Update("totp_recovery_codes").
+		Set("used_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"code_hash": codeHash}).
+		Where(sq.Eq{"used_at": nil}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, consts.ErrFailedToBuildSQL
+	}
+
+	result, err := r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, consts.ErrFailedTOTP
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, consts.ErrFailedTOTP
+	}
+
+	return rows == 1, nil
+}
+
+func (r *TOTPRepository) Delete(ctx context.Context, userID uint) error {
+	query, args, err := sq.
+		Delete("user_totp").
+		Where(sq.Eq{"user_id": userID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedTOTP
+	}
+
+	query, args, err = sq.
+		Delete("totp_recovery_codes").
+		Where(sq.Eq{"user_id": userID}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedTOTP
+	}
+
+	return nil
+}
+
+func (r *TOTPRepository) CreateChallenge(ctx context.Context, c *model.MFAChallenge) error {
+	query, args, err := sq.
+		Insert("mfa_challenges").
+		Columns("user_id", "token_hash", "expires_at").
+		Values(c.UserID, c.TokenHash, c.ExpiresAt).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedTOTP
+	}
+
+	return nil
+}
+
+func (r *TOTPRepository) GetChallenge(ctx context.Context, tokenHash string) (*model.MFAChallenge, error) {
+	query, args, err := sq.
+		Select("id", "user_id", "attempts", "expires_at").
+		From("mfa_challenges").
+		Where(sq.Eq{"token_hash": tokenHash}).
+		Where(sq.Expr("expires_at > NOW()")).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, consts.ErrFailedToBuildSQL
+	}
+
+	var c model.MFAChallenge
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(&c.ID, &c.UserID, &c.Attempts, &c.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, consts.ErrInvalidChallenge
+	}
+	if err != nil {
+		return nil, consts.ErrFailedTOTP
+	}
+
+	return &c, nil
+}
+
+// FailChallenge counts a wrong code and drops the challenge once maxAttempts
+// is reached.
+func (r *TOTPRepository) FailChallenge(ctx context.Context, id uint, maxAttempts int) error {
+	query, args, err := sq.
+		Update("mfa_challenges").
+		Set("attempts", sq.Expr("attempts + 1")).
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedTOTP
+	}
+
+	query, args, err = sq.
+		Delete("mfa_challenges").
+		Where(sq.Eq{"id": id}).
+		Where(sq.GtOrEq{"attempts": maxAttempts}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	_, err = r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedTOTP
+	}
+
+	return nil
+}
+
+// ConsumeChallenge deletes the challenge and reports whether this call was
+// the one that removed it, so a challenge can only be completed once.
+func (r *TOTPRepository) ConsumeChallenge(ctx context.Context, id uint) (bool, error) {
+	query, args, err := sq.
+		Delete("mfa_challenges").
+		Where(sq.Eq{"id": id}).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return false, consts.ErrFailedToBuildSQL
+	}
+
+	result, err := r.Database.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, consts.ErrFailedTOTP
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, consts.ErrFailedTOTP
+	}
+
+	return rows == 1, nil
+}
@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/postgres"
+	"auth-service/pkg/consts"
+	"context"
+	"database/sql"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+type UserRepository struct {
+	Database *postgres.Db
+}
+
+func NewUserRepository(db *postgres.Db) *UserRepository {
+	return &UserRepository{
+		Database: db,
+	}
+}
+
+// likeEscaper makes LIKE wildcards in user input match literally, backslash
+// being the default LIKE escape character in Postgres.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+func (r *UserRepository) List(ctx context.Context, search string, limit uint64, offset uint64) ([]model.User, uint, error) {
+	filter := sq.And{}
+	if search != "" {
+		filter = append(filter, sq.ILike{"username": "%" + escapeLike(search) + "%"})
+	}
+
+	query, args, err := sq.
+		Select("COUNT(*)").
+		From("users").
+		Where(filter).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, 0, consts.ErrFailedToBuildSQL
+	}
+
+	var total uint
+
+	err = r.Database.DB.QueryRowContext(ctx, query, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, consts.ErrFailedListUsers
+	}
+
+	query, args, err = sq.
+		Select("id", "username", "email", "role", "service_account", "disabled_at", "deletion_scheduled_at", "created_at").
+		From("users").
+		Where(filter).
+		OrderBy("id").
+		Limit(limit).
+		Offset(offset).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, 0, consts.ErrFailedToBuildSQL
+	}
+
+	rows, err := r.Database.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, consts.ErrFailedListUsers
+	}
+
+	defer rows.Close()
+
+	users := []model.User{}
+
+	for rows.Next() {
+		var user model.User
+		err := rows.Scan(
+			&user.ID,
+			&user.UserName,
+			&user.Email,
+			&user.Role,
+			&user.ServiceAccount,
+			&user.DisabledAt,
+			&user.DeletionScheduledAt,
+			&user.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, consts.ErrFailedListUsers
+		}
+		users = append(users, user)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, 0, consts.ErrFailedListUsers
+	}
+
+	return users, total, nil
+}
+
+// UpdateRole changes the user's role. Demoting the last active admin fails
+// with consts.ErrLastAdmin.
+func (r *UserRepository) UpdateRole(ctx context.Context, id uint, role string) error {
+	builder := sq.Update("users").Set("role", role).Where(sq.Eq{"id": id})
+
+	return r.exec(ctx, id, role != consts.RoleAdmin, builder.PlaceholderFormat(sq.Dollar), consts.ErrFailedUpdateUser)
+}
+
+// SetDisabled disables or enables the user. Disabling the last active admin
+// fails with consts.ErrLastAdmin.
+func (r *UserRepository) SetDisabled(ctx context.Context, id uint, disabled bool) error {
+	builder := sq.Update("users").Where(sq.Eq{"id": id})
+
+	if disabled {
+		builder = builder.Set("disabled_at", sq.Expr("COALESCE(disabled_at, NOW())"))
+	} else {
+		builder = builder.Set("disabled_at", nil)
+	}
+
+	return r.exec(ctx, id, disabled, builder.PlaceholderFormat(sq.Dollar), consts.ErrFailedUpdateUser)
+}
+
+// Delete removes the user. Deleting the last active admin fails with
+// consts.ErrLastAdmin.
+func (r *UserRepository) Delete(ctx context.Context, id uint) error {
+	builder := sq.Delete("users").Where(sq.Eq{"id": id})
+
+	return r.exec(ctx, id, true, builder.PlaceholderFormat(sq.Dollar), consts.ErrFailedDeleteUser)
+}
+
+// exec runs builder against the user id in a transaction. With guarded set,
+// it first makes sure the change does not remove the last active admin.
+func (r *UserRepository) exec(ctx context.Context, id uint, guarded bool, builder sq.Sqlizer, failErr error) error {
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	tx, err := r.Database.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return consts.ErrFailedToBeginTx
+	}
+
+	defer tx.Rollback()
+
+	if guarded {
+		err = guardLastAdmin(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return failErr
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return failErr
+	}
+
+	if rows == 0 {
+		return consts.ErrUserNotFound
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return consts.ErrFailedToCommitTx
+	}
+
+	return nil
+}
+
+// guardLastAdmin fails with consts.ErrLastAdmin if id is an active admin and
+// no other admin would be left active: enabled and not scheduled for deletion.
+// The active admins stay locked until tx ends, so concurrent changes to two
+// different admins cannot both pass the check.
+func guardLastAdmin(ctx context.Context, tx *sql.Tx, id uint) error {
+	query, args, err := sq.
+		Select("id").
+		From("users").
+		Where(sq.Eq{"role": consts.RoleAdmin, "disabled_at": nil}).
+		Where(sq.Or{
+			sq.Eq{"deletion_scheduled_at": nil},
+			sq.Eq{"id": id},
+		}).
+		Suffix("FOR UPDATE").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return consts.ErrFailedToBuildSQL
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return consts.ErrFailedCheckAdmin
+	}
+
+	defer rows.Close()
+
+	target, others := false, 0
+
+	for rows.Next() {
+		var adminID uint
+		err := rows.Scan(&adminID)
+		if err != nil {
+			return consts.ErrFailedCheckAdmin
+		}
+
+		if adminID == id {
+			target = true
+		} else {
+			others++
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return consts.ErrFailedCheckAdmin
+	}
+
+	if target && others == 0 {
+		return consts.ErrLastAdmin
+	}
+
+	return nil
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"auth-service/internal/payload"
+	"auth-service/internal/repository"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/mail"
+	"auth-service/pkg/password"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type AccountService struct {
+	AuthRepository    *repository.AuthRepository
+	AccountRepository *repository.AccountRepository
+	APIKeyRepository  *repository.APIKeyRepository
+	UserRepository    *repository.UserRepository
+	ThrottleService   *ThrottleService
+	TOTPService       *TOTPService
+	MailSender        mail.Sender
+	deletionDelay     time.Duration
+}
+
+func NewAccountService(
+	authRepository *repository.AuthRepository,
+	accountRepository *repository.AccountRepository,
+	apiKeyRepository *repository.APIKeyRepository,
+	userRepository *repository.UserRepository,
+	throttleService *ThrottleService,
+	totpService *TOTPService,
+	mailSender mail.Sender,
+	deletionDelay time.Duration,
+) *AccountService {
+	return &AccountService{
+		AuthRepository:    authRepository,
+		AccountRepository: accountRepository,
+		APIKeyRepository:  apiKeyRepository,
+		UserRepository:    userRepository,
+		ThrottleService:   throttleService,
+		TOTPService:       totpService,
+		MailSender:        mailSender,
+		deletionDelay:     deletionDelay,
+	}
+}
+
+// Export collects everything tied to the user's account.
+func (s *AccountService) Export(ctx context.Context, userID uint) (*payload.AccountExport, error) {
+	user, err := s.AuthRepository.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	totpEnabled, err := s.TOTPService.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := s.AccountRepository.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys, err := s.APIKeyRepository.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	consents, err := s.AccountRepository.ListConsents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := s.AccountRepository.ListInvitations(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payload.AccountExport{
+		ExportedAt:    time.Now().UTC(),
+		User:          *user,
+		TOTPEnabled:   totpEnabled,
+		Sessions:      sessions,
+		APIKeys:       apiKeys,
+		OAuthConsents: consents,
+		Invitations:   invitations,
+	}, nil
+}
+
+// RequestDeletion schedules the account for deletion after the cooling-off
+// period. The user keeps full access until then and can cancel at any time.
+// Wrong passwords count as failed logins, as in PasswordService.Change.
+func (s *AccountService) RequestDeletion(ctx context.Context, userID uint, ip string, p *payload.DeleteAccountPayload) (time.Time, error) {
+	user, err := s.AuthRepository.GetUserByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	err = s.ThrottleService.Check(ctx, user.UserName, ip)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ok, _, err := password.Verify(p.Password, user.PasswordHash)
+	if err != nil || !ok {
+		err = s.ThrottleService.RecordFailure(ctx, user.UserName, ip)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Time{}, consts.ErrInvalidCredentials
+	}
+
+	scheduledAt, err := s.AccountRepository.ScheduleDeletion(ctx, userID, time.Now().Add(s.deletionDelay))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if user.Email != nil {
+		err = s.MailSender.Send(ctx, mail.Message{
+			To:      *user.Email,
+			Subject: "Account deletion scheduled",
+			Body: fmt.Sprintf(
+				"Hello %s,\n\nyour account will be deleted on %s. Until then you can sign in and cancel the deletion.\n\nIf you did not request this, sign in, cancel the deletion and change your password.",
+				user.UserName,
+				scheduledAt.UTC().Format(time.RFC1123),
+			),
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "Send account deletion mail error", "error", err)
+		}
+	}
+
+	return scheduledAt, nil
+}
+
+func (s *AccountService) CancelDeletion(ctx context.Context, userID uint) error {
+	return s.AccountRepository.CancelDeletion(ctx, userID)
+}
+
+// PurgeDue deletes the accounts whose cooling-off period is over. Removing the
+// users row cascades to sessions, tokens, keys and consents, and clears the
+// user from invitations and OAuth clients they created.
+func (s *AccountService) PurgeDue(ctx context.Context) (int, error) {
+	users, err := s.AccountRepository.ListDueDeletions(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+
+	for _, user := range users {
+		err = s.UserRepository.Delete(ctx, user.ID)
+		if errors.Is(err, consts.ErrUserNotFound) {
+			continue
+		}
+		if errors.Is(err, consts.ErrLastAdmin) {
+			slog.WarnContext(ctx, "Keeping the last active admin scheduled for deletion", "user_id", user.ID)
+			continue
+		}
+		if err != nil {
+			return purged, err
+		}
+
+		err = s.ThrottleService.Reset(ctx, user.UserName)
+		if err != nil {
+			slog.ErrorContext(ctx, "Clear login attempts of deleted account error", "error", err)
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
+
+// Run purges due accounts every interval.
+func (s *AccountService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.PurgeDue(ctx)
+			if err != nil {
+				slog.Error("Purge deleted accounts error", "error", err)
+			}
+			if purged > 0 {
+				slog.Info("Deleted accounts after their cooling-off period", "count", purged)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/payload"
+	"auth-service/internal/repository"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/token"
+	"context"
+	"slices"
+	"time"
+)
+
+type APIKeyService struct {
+	AuthRepository   *repository.AuthRepository
+	APIKeyRepository *repository.APIKeyRepository
+	RoleService      *RoleService
+}
+
+func NewAPIKeyService(
+	authRepository *repository.AuthRepository,
+	apiKeyRepository *repository.APIKeyRepository,
+	roleService *RoleService,
+) *APIKeyService {
+	return &APIKeyService{
+		AuthRepository:   authRepository,
+		APIKeyRepository: apiKeyRepository,
+		RoleService:      roleService,
+	}
+}
+
+// Create issues a new key for the user. The plain key is returned only here;
+// auth-service keeps just its hash and a short prefix to tell keys apart.
+func (s *APIKeyService) Create(ctx context.Context, userID uint, p *payload.CreateAPIKeyPayload) (*payload.CreateAPIKeyResponse, error) {
+	secret, err := token.Generate()
+	if err != nil {
+		return nil, consts.ErrFailedCreateAPIKey
+	}
+
+	key := consts.APIKeyPrefix + secret
+
+	scopes := slices.Clone(p.Scopes)
+	slices.Sort(scopes)
+
+	apiKey := model.APIKey{
+		UserID:  userID,
+		Name:    p.Name,
+		Prefix:  key[:len(consts.APIKeyPrefix)+8],
+		KeyHash: token.Hash(key),
+		Scopes:  slices.Compact(scopes),
+	}
+
+	if p.ExpiresInDays != nil {
+		expiresAt := time.Now().AddDate(0, 0, *p.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	err = s.APIKeyRepository.Create(ctx, &apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payload.CreateAPIKeyResponse{
+		APIKey: apiKey,
+		Key:    key,
+	}, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, userID uint) ([]model.APIKey, error) {
+	return s.APIKeyRepository.ListByUser(ctx, userID)
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, userID uint, id uint) error {
+	return s.APIKeyRepository.Revoke(ctx, userID, id)
+}
+
+func (s *APIKeyService) CreateServiceAccount(ctx context.Context, p *payload.CreateServiceAccountPayload) (*model.User, error) {
+	role := p.Role
+	if role == "" {
+		role = consts.RoleUser
+	}
+
+	err := s.RoleService.Validate(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := s.AuthRepository.CreateServiceAccount(ctx, p.Name, role)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.AuthRepository.GetUserByID(ctx, userID)
+}
+
+// ServiceAccount returns the user only if it is a service account, so the
+// admin key endpoints cannot be used to mint keys for regular users.
+func (s *APIKeyService) ServiceAccount(ctx context.Context, id uint) (*model.User, error) {
+	user, err := s.AuthRepository.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.ServiceAccount {
+		return nil, consts.ErrNotServiceAccount
+	}
+
+	return user, nil
+}
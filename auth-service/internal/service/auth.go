@@ -1,59 +1,482 @@
 package service
 
 import (
+	"auth-service/internal/model"
 	"auth-service/internal/payload"
 	"auth-service/internal/repository"
 	"auth-service/pkg/consts"
 	"auth-service/pkg/jwt"
+	"auth-service/pkg/metrics"
+	"auth-service/pkg/password"
+	"auth-service/pkg/token"
 	"context"
-
-	"golang.org/x/crypto/bcrypt"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
 )
 
 type AuthService struct {
-	AuthRepository *repository.AuthRepository
+	AuthRepository         *repository.AuthRepository
+	RefreshTokenRepository *repository.RefreshTokenRepository
+	RevocationRepository   *repository.RevocationRepository
+	SessionRepository      *repository.SessionRepository
+	KeyService             *KeyService
+	ThrottleService        *ThrottleService
+	TOTPService            *TOTPService
+	PasswordPolicy         *password.Policy
 }
 
-func NewAuthService(authRepository *repository.AuthRepository) *AuthService {
+func NewAuthService(
+	authRepository *repository.AuthRepository,
+	refreshTokenRepository *repository.RefreshTokenRepository,
+	revocationRepository *repository.RevocationRepository,
+	sessionRepository *repository.SessionRepository,
+	keyService *KeyService,
+	throttleService *ThrottleService,
+	totpService *TOTPService,
+	passwordPolicy *password.Policy,
+) *AuthService {
 	return &AuthService{
-		AuthRepository: authRepository,
+		AuthRepository:         authRepository,
+		RefreshTokenRepository: refreshTokenRepository,
+		RevocationRepository:   revocationRepository,
+		SessionRepository:      sessionRepository,
+		KeyService:             keyService,
+		ThrottleService:        throttleService,
+		TOTPService:            totpService,
+		PasswordPolicy:         passwordPolicy,
 	}
 }
 
 func (s *AuthService) Register(ctx context.Context, p *payload.AuthRegisterPayload) (uint, error) {
+	err := s.PasswordPolicy.Check(p.Password)
+	if err != nil {
+		return 0, err
+	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(p.Password), bcrypt.DefaultCost)
+	hashedPassword, err := password.Hash(p.Password)
 	if err != nil {
 		return 0, consts.ErrFailedHashedPassword
 	}
 
-	p.Password = string(hashedPassword)
+	p.Password = hashedPassword
+
+	if p.Email != nil {
+		email := strings.ToLower(*p.Email)
+		p.Email = &email
+	}
 
-	userID, err := s.AuthRepository.Register(ctx, p)
+	userID, err := s.AuthRepository.Register(ctx, p, consts.RoleUser)
 	if err != nil {
 		return 0, err
 	}
 
+	metrics.Registrations.Inc()
+
 	return userID, nil
 
 }
 
-func (s *AuthService) Login(ctx context.Context, p *payload.AuthLoginPayload) (string, error) {
+// BootstrapAdmin creates the first admin account. It does nothing once any
+// admin exists, so the bootstrap credentials cannot be used to mint more.
+func (s *AuthService) BootstrapAdmin(ctx context.Context, username string, plainPassword string) (bool, error) {
+	exists, err := s.AuthRepository.HasAdmin(ctx)
+	if err != nil || exists {
+		return false, err
+	}
+
+	err = s.PasswordPolicy.Check(plainPassword)
+	if err != nil {
+		return false, err
+	}
+
+	hashedPassword, err := password.Hash(plainPassword)
+	if err != nil {
+		return false, consts.ErrFailedHashedPassword
+	}
+
+	p := &payload.AuthRegisterPayload{
+		Username: username,
+		Password: hashedPassword,
+	}
+
+	_, err = s.AuthRepository.Register(ctx, p, consts.RoleAdmin)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *AuthService) Login(ctx context.Context, p *payload.AuthLoginPayload, client model.ClientInfo) (*payload.AuthLoginResponse, error) {
+	err := s.ThrottleService.Check(ctx, p.Username, client.IP)
+	if errors.Is(err, consts.ErrTooManyAttempts) {
+		metrics.LoginFailures.WithLabelValues("throttled").Inc()
+	}
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := s.AuthRepository.GetUserByUsername(ctx, p.Username)
+	if errors.Is(err, consts.ErrUserNotFound) {
+		return nil, s.loginFailed(ctx, p.Username, client.IP)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ok, needsRehash, err := password.Verify(p.Password, user.PasswordHash)
+	if err != nil || !ok {
+		return nil, s.loginFailed(ctx, p.Username, client.IP)
+	}
+
+	if needsRehash {
+		s.rehashPassword(ctx, user.ID, p.Password)
+	}
+
+	if user.DisabledAt != nil {
+		metrics.LoginFailures.WithLabelValues("disabled").Inc()
+		return nil, consts.ErrUserDisabled
+	}
+
+	mfaEnabled, err := s.TOTPService.IsEnabled(ctx, user.ID)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	// With TOTP the failure counter is reset only once the second factor
+	// passes, so the code cannot be guessed under a freshly reset counter.
+	if mfaEnabled {
+		challengeToken, err := s.TOTPService.CreateChallenge(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &payload.AuthLoginResponse{
+			MFARequired:    true,
+			ChallengeToken: challengeToken,
+		}, nil
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(p.Password))
+	err = s.ThrottleService.Reset(ctx, p.Username)
 	if err != nil {
-		return "", consts.ErrInvalidCredentials
+		return nil, err
 	}
 
-	token, err := jwt.GenerateToken(user.ID, user.Role)
+	data, err := s.startSession(ctx, user, &model.Session{UserAgent: client.UserAgent, IP: client.IP})
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.Logins.Inc()
+
+	return data, nil
+}
+
+// LoginTOTP finishes a login started by Login for a user with TOTP enabled.
+func (s *AuthService) LoginTOTP(ctx context.Context, p *payload.TOTPLoginPayload, client model.ClientInfo) (*payload.AuthLoginResponse, error) {
+	userID, err := s.TOTPService.ChallengeUser(ctx, p.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.AuthRepository.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Wrong codes count against the same username and IP limits as wrong
+	// passwords.
+	err = s.ThrottleService.Check(ctx, user.UserName, client.IP)
+	if errors.Is(err, consts.ErrTooManyAttempts) {
+		metrics.LoginFailures.WithLabelValues("throttled").Inc()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.TOTPService.CompleteChallenge(ctx, p.ChallengeToken, p.Code)
+	if errors.Is(err, consts.ErrInvalidTOTPCode) {
+		metrics.LoginFailures.WithLabelValues("invalid_totp").Inc()
+
+		failErr := s.ThrottleService.RecordFailure(ctx, user.UserName, client.IP)
+		if failErr != nil {
+			return nil, failErr
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.ThrottleService.Reset(ctx, user.UserName)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DisabledAt != nil {
+		metrics.LoginFailures.WithLabelValues("disabled").Inc()
+		return nil, consts.ErrUserDisabled
+	}
+
+	data, err := s.startSession(ctx, user, &model.Session{UserAgent: client.UserAgent, IP: client.IP})
+	if err != nil {
+		return nil, err
+	}
+
+	metrics.Logins.Inc()
+
+	return data, nil
+}
+
+// startSession records session as a new session of user and issues its first
+// token pair. The session ID doubles as the refresh token family ID and is set
+// on session.
+func (s *AuthService) startSession(ctx context.Context, user *model.User, session *model.Session) (*payload.AuthLoginResponse, error) {
+	sessionID, err := token.Generate()
+	if err != nil {
+		return nil, consts.ErrGenerateToken
+	}
+
+	refreshToken, err := token.Generate()
+	if err != nil {
+		return nil, consts.ErrGenerateToken
+	}
+
+	expiresAt := time.Now().Add(consts.RefreshTokenTTL)
+
+	session.ID = sessionID
+	session.UserID = user.ID
+	session.ExpiresAt = expiresAt
+
+	err = s.SessionRepository.Create(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.RefreshTokenRepository.Create(ctx, &model.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: token.Hash(refreshToken),
+		FamilyID:  sessionID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueAccessToken(user, session, refreshToken)
+}
+
+// rehashPassword replaces a legacy bcrypt or outdated argon2id hash after a
+// successful login, the only moment the plain password is known. A failure
+// only delays the upgrade to the next login, so it does not fail the login.
+func (s *AuthService) rehashPassword(ctx context.Context, userID uint, plainPassword string) {
+	hashedPassword, err := password.Hash(plainPassword)
 	if err != nil {
-		return "", consts.ErrGenerateToken
+		slog.ErrorContext(ctx, "Rehash password error", "user_id", userID, "error", err)
+		return
 	}
 
-	return token, nil
+	err = s.AuthRepository.UpdatePassword(ctx, userID, hashedPassword)
+	if err != nil {
+		slog.ErrorContext(ctx, "Rehash password error", "user_id", userID, "error", err)
+	}
+}
+
+func (s *AuthService) loginFailed(ctx context.Context, username string, ip string) error {
+	metrics.LoginFailures.WithLabelValues("invalid_credentials").Inc()
+
+	err := s.ThrottleService.RecordFailure(ctx, username, ip)
+	if err != nil {
+		return err
+	}
+
+	return consts.ErrInvalidCredentials
+}
+
+func (s *AuthService) Refresh(ctx context.Context, p *payload.AuthRefreshPayload, client model.ClientInfo) (*payload.AuthLoginResponse, error) {
+	return s.rotateSession(ctx, p.RefreshToken, "", client)
+}
+
+// rotateSession exchanges a refresh token for a new token pair. clientID is
+// the OAuth client the session must belong to, or empty for a first-party
+// login, so refresh tokens of one kind cannot be redeemed as the other.
+func (s *AuthService) rotateSession(ctx context.Context, plainToken string, clientID string, client model.ClientInfo) (*payload.AuthLoginResponse, error) {
+	tokenHash := token.Hash(plainToken)
+
+	// Every refresh token belongs to a session, so no session means no such
+	// token.
+	session, err := s.SessionRepository.GetByRefreshToken(ctx, tokenHash)
+	if errors.Is(err, consts.ErrSessionNotFound) {
+		return nil, consts.ErrRefreshTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sessionClientID := ""
+	if session.ClientID != nil {
+		sessionClientID = *session.ClientID
+	}
+
+	if sessionClientID != clientID {
+		return nil, consts.ErrRefreshTokenNotFound
+	}
+
+	refreshToken, err := token.Generate()
+	if err != nil {
+		return nil, consts.ErrGenerateToken
+	}
+
+	rotated, err := s.RefreshTokenRepository.Rotate(
+		ctx,
+		tokenHash,
+		token.Hash(refreshToken),
+		time.Now().Add(consts.RefreshTokenTTL),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.AuthRepository.GetUserByID(ctx, rotated.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DisabledAt != nil {
+		return nil, consts.ErrUserDisabled
+	}
+
+	err = s.SessionRepository.Touch(ctx, rotated.FamilyID, client, rotated.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	session.ID = rotated.FamilyID
+
+	return s.issueAccessToken(user, session, refreshToken)
+}
+
+func (s *AuthService) Logout(ctx context.Context, claims *jwt.Claims, p *payload.AuthLogoutPayload) error {
+	err := s.RevocationRepository.RevokeToken(ctx, claims.ID, claims.UserID, claims.ExpiresAt.Time)
+	if err != nil {
+		return err
+	}
+
+	if claims.SessionID != "" {
+		err = s.SessionRepository.Revoke(ctx, claims.UserID, claims.SessionID)
+		if err != nil && !errors.Is(err, consts.ErrSessionNotFound) {
+			return err
+		}
+	}
+
+	if p.RefreshToken == "" {
+		return nil
+	}
+
+	return s.RefreshTokenRepository.RevokeFamilyByHash(ctx, token.Hash(p.RefreshToken), claims.UserID)
+}
+
+func (s *AuthService) Authenticate(ctx context.Context, tokenStr string) (*jwt.Claims, error) {
+	claims, err := jwt.ParseToken(tokenStr, s.KeyService.Lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.IsOAuth() {
+		return nil, consts.ErrOAuthTokenNotAllowed
+	}
+
+	err = s.checkRevoked(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// checkRevoked returns ErrTokenRevoked or ErrSessionRevoked if the token, its
+// session or all tokens of its user were revoked. Client credentials tokens
+// have no user; whether their client is still active is up to the caller.
+func (s *AuthService) checkRevoked(ctx context.Context, claims *jwt.Claims) error {
+	if claims.UserID == 0 {
+		return nil
+	}
+
+	revoked, err := s.RevocationRepository.IsRevoked(ctx, claims.ID, claims.UserID, claims.IssuedAt.Time)
+	if err != nil {
+		return err
+	}
+
+	if revoked {
+		return consts.ErrTokenRevoked
+	}
+
+	if claims.SessionID != "" {
+		revoked, err = s.SessionRepository.IsRevoked(ctx, claims.SessionID)
+		if err != nil {
+			return err
+		}
+
+		if revoked {
+			return consts.ErrSessionRevoked
+		}
+	}
+
+	return nil
+}
+
+// Me returns the profile of the authenticated user.
+func (s *AuthService) Me(ctx context.Context, userID uint) (*payload.MeResponse, error) {
+	user, err := s.AuthRepository.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	totpEnabled, err := s.TOTPService.IsEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payload.MeResponse{
+		User:        *user,
+		TOTPEnabled: totpEnabled,
+	}, nil
+}
+
+// issueAccessToken signs an access token for user in session. Tokens of an
+// OAuth session carry only the granted scopes the user still holds.
+func (s *AuthService) issueAccessToken(user *model.User, session *model.Session, refreshToken string) (*payload.AuthLoginResponse, error) {
+	key, err := s.KeyService.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := jwt.Claims{
+		UserID:      user.ID,
+		Role:        user.Role,
+		Permissions: user.Permissions,
+		SessionID:   session.ID,
+	}
+
+	if session.ClientID != nil {
+		claims.ClientID = *session.ClientID
+		claims.Scope = strings.Join(session.Scopes, " ")
+		claims.Permissions = slices.DeleteFunc(slices.Clone(session.Scopes), func(scope string) bool {
+			return !slices.Contains(user.Permissions, scope)
+		})
+	}
+
+	accessToken, err := jwt.GenerateToken(key, claims)
+	if err != nil {
+		return nil, consts.ErrGenerateToken
+	}
 
+	return &payload.AuthLoginResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(consts.AccessTokenTTL.Seconds()),
+	}, nil
 }
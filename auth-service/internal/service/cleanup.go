@@ -0,0 +1,49 @@
+package service
+
+import (
+	"auth-service/internal/repository"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// CleanupService deletes expired sessions, tokens, challenges and codes,
+// which nothing else removes. Expired rows are already rejected everywhere,
+// so how often this runs only bounds the size of the tables.
+type CleanupService struct {
+	CleanupRepository *repository.CleanupRepository
+}
+
+func NewCleanupService(cleanupRepository *repository.CleanupRepository) *CleanupService {
+	return &CleanupService{
+		CleanupRepository: cleanupRepository,
+	}
+}
+
+func (s *CleanupService) DeleteExpired(ctx context.Context) {
+	for _, table := range repository.ExpiringTables {
+		deleted, err := s.CleanupRepository.DeleteExpired(ctx, table)
+		if err != nil {
+			slog.ErrorContext(ctx, "Delete expired records error", "table", table, "error", err)
+			continue
+		}
+		if deleted > 0 {
+			slog.InfoContext(ctx, "Deleted expired records", "table", table, "count", deleted)
+		}
+	}
+}
+
+// Run deletes expired records every interval.
+func (s *CleanupService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.DeleteExpired(ctx)
+		}
+	}
+}
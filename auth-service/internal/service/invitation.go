@@ -0,0 +1,62 @@
+package service
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/payload"
+	"auth-service/internal/repository"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/token"
+	"context"
+	"time"
+)
+
+type InvitationService struct {
+	InvitationRepository *repository.InvitationRepository
+	RoleService          *RoleService
+}
+
+func NewInvitationService(invitationRepository *repository.InvitationRepository, roleService *RoleService) *InvitationService {
+	return &InvitationService{
+		InvitationRepository: invitationRepository,
+		RoleService:          roleService,
+	}
+}
+
+func (s *InvitationService) Create(ctx context.Context, adminID uint, p *payload.CreateInvitationPayload) (*payload.CreateInvitationResponse, error) {
+	role := p.Role
+	if role == "" {
+		role = consts.RoleAdmin
+	}
+
+	err := s.RoleService.Validate(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := token.Generate()
+	if err != nil {
+		return nil, consts.ErrFailedCreateInvitation
+	}
+
+	invitation := &model.Invitation{
+		CodeHash:  token.Hash(code),
+		Role:      role,
+		CreatedBy: adminID,
+		ExpiresAt: time.Now().Add(consts.InvitationTTL),
+	}
+
+	err = s.InvitationRepository.Create(ctx, invitation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payload.CreateInvitationResponse{
+		Code:      code,
+		Role:      invitation.Role,
+		ExpiresAt: invitation.ExpiresAt,
+	}, nil
+}
+
+func (s *InvitationService) Redeem(ctx context.Context, userID uint, p *payload.RedeemInvitationPayload) (string, error) {
+	return s.InvitationRepository.Redeem(ctx, token.Hash(p.Code), userID)
+}
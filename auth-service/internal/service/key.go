@@ -0,0 +1,266 @@
+package service
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/jwt"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// KeyService keeps the signing keys from the database in memory. The newest
+// active key signs new tokens; keys retired within the grace period still
+// verify tokens and are published in the JWKS. A rotated key is published
+// prePublish before it signs, so verifiers refreshing their JWKS know it by
+// then. Private keys are stored sealed by cipher and only ever exist in the
+// clear in memory.
+type KeyService struct {
+	SigningKeyRepository *repository.SigningKeyRepository
+	cipher               *jwt.KeyCipher
+	algorithm            string
+	grace                time.Duration
+	rotationInterval     time.Duration
+	prePublish           time.Duration
+
+	mu        sync.RWMutex
+	current   *jwt.SigningKey
+	currentAt time.Time
+	pending   bool
+	keys      map[string]*jwt.SigningKey
+	order     []string
+}
+
+func NewKeyService(
+	signingKeyRepository *repository.SigningKeyRepository,
+	cipher *jwt.KeyCipher,
+	algorithm string,
+	grace time.Duration,
+	rotationInterval time.Duration,
+	prePublish time.Duration,
+) *KeyService {
+	return &KeyService{
+		SigningKeyRepository: signingKeyRepository,
+		cipher:               cipher,
+		algorithm:            algorithm,
+		grace:                grace,
+		rotationInterval:     rotationInterval,
+		prePublish:           max(prePublish, consts.MinKeyPrePublishPeriod),
+		keys:                 make(map[string]*jwt.SigningKey),
+	}
+}
+
+// Load reads usable keys from the database and creates a key right away when
+// none can sign.
+func (s *KeyService) Load(ctx context.Context) error {
+	stored, err := s.SigningKeyRepository.GetUsable(ctx, s.grace)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	canSign := false
+	for _, key := range stored {
+		if signs(&key, now) {
+			canSign = true
+			break
+		}
+	}
+
+	if !canSign {
+		_, err = s.rotate(ctx, 0, time.Time{})
+		if err != nil {
+			return err
+		}
+		return s.Load(ctx)
+	}
+
+	keys := make(map[string]*jwt.SigningKey, len(stored))
+	order := make([]string, 0, len(stored))
+
+	var (
+		current   *jwt.SigningKey
+		currentAt time.Time
+		pending   bool
+	)
+
+	for _, key := range stored {
+		parsed, err := s.open(ctx, &key)
+		if err != nil {
+			slog.Warn("Skipping signing key", "kid", key.KID, "error", err)
+			continue
+		}
+
+		keys[parsed.ID] = parsed
+		order = append(order, parsed.ID)
+
+		if key.ActivatesAt.After(now) {
+			pending = true
+		}
+
+		if current == nil && signs(&key, now) {
+			current = parsed
+			currentAt = key.ActivatesAt
+		}
+	}
+
+	if current == nil {
+		return consts.ErrNoSigningKey
+	}
+
+	s.mu.Lock()
+	s.current = current
+	s.currentAt = currentAt
+	s.pending = pending
+	s.keys = keys
+	s.order = order
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Rotate publishes a new key that takes over from the active one once the
+// pre-publish period is over.
+func (s *KeyService) Rotate(ctx context.Context) (*model.SigningKey, error) {
+	return s.rotate(ctx, s.prePublish, time.Time{})
+}
+
+// rotate creates a key that starts signing after prePublish. It does nothing
+// and returns nil if a key activating after activatedAfter already exists.
+func (s *KeyService) rotate(ctx context.Context, prePublish time.Duration, activatedAfter time.Time) (*model.SigningKey, error) {
+	key, err := jwt.NewSigningKey(s.algorithm)
+	if err != nil {
+		return nil, consts.ErrFailedRotateSigningKey
+	}
+
+	privateKey, err := key.MarshalPrivateKey()
+	if err != nil {
+		return nil, consts.ErrFailedRotateSigningKey
+	}
+
+	privateKey, err = s.cipher.Seal(key.ID, privateKey)
+	if err != nil {
+		return nil, consts.ErrFailedRotateSigningKey
+	}
+
+	stored := &model.SigningKey{
+		KID:         key.ID,
+		Algorithm:   key.Algorithm,
+		PrivateKey:  privateKey,
+		ActivatesAt: time.Now().Add(prePublish),
+	}
+
+	rotated, err := s.SigningKeyRepository.Rotate(ctx, stored, activatedAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rotated {
+		return nil, nil
+	}
+
+	return stored, s.Load(ctx)
+}
+
+// signs reports whether key is the one that signs at now: activated and not
+// retired yet.
+func signs(key *model.SigningKey, now time.Time) bool {
+	return !key.ActivatesAt.After(now) && (key.RetiredAt == nil || key.RetiredAt.After(now))
+}
+
+// open decrypts and parses a stored key. A key stored in the clear by an
+// older version is sealed in place, so the database stops holding it as
+// plain PEM.
+func (s *KeyService) open(ctx context.Context, key *model.SigningKey) (*jwt.SigningKey, error) {
+	if !jwt.IsSealed(key.PrivateKey) {
+		parsed, err := jwt.ParseSigningKey(key.KID, key.Algorithm, key.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+
+		sealed, err := s.cipher.Seal(key.KID, key.PrivateKey)
+		if err == nil {
+			err = s.SigningKeyRepository.SetPrivateKey(ctx, key.KID, sealed)
+		}
+		if err != nil {
+			slog.Warn("Encrypt signing key error", "kid", key.KID, "error", err)
+		}
+
+		return parsed, nil
+	}
+
+	privateKey, err := s.cipher.Open(key.KID, key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return jwt.ParseSigningKey(key.KID, key.Algorithm, privateKey)
+}
+
+// Run reloads keys every interval so replicas pick up rotations made
+// elsewhere, and rotates the active key once it is older than the rotation
+// interval.
+func (s *KeyService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.Load(ctx)
+			if err != nil {
+				slog.Error("Reload signing keys error", "error", err)
+				continue
+			}
+
+			s.mu.RLock()
+			due := s.rotationInterval > 0 && !s.pending && time.Since(s.currentAt) > s.rotationInterval
+			s.mu.RUnlock()
+
+			if due {
+				// Another replica may have rotated since the reload, the
+				// repository checks again under its lock.
+				_, err = s.rotate(ctx, s.prePublish, time.Now().Add(-s.rotationInterval))
+				if err != nil {
+					slog.Error("Rotate signing key error", "error", err)
+				}
+			}
+		}
+	}
+}
+
+func (s *KeyService) Current() (*jwt.SigningKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.current == nil {
+		return nil, consts.ErrNoSigningKey
+	}
+
+	return s.current, nil
+}
+
+func (s *KeyService) Lookup(kid string) (*jwt.SigningKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (s *KeyService) JWKS() *jwt.JWKS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jwks := &jwt.JWKS{Keys: make([]jwt.JWK, 0, len(s.order))}
+	for _, kid := range s.order {
+		jwks.Keys = append(jwks.Keys, s.keys[kid].JWK())
+	}
+
+	return jwks
+}
@@ -0,0 +1,572 @@
+package service
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/payload"
+	"auth-service/internal/repository"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/jwt"
+	"auth-service/pkg/token"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OAuthError is an error answered in the RFC 6749 error format. Code is one of
+// the error codes defined there, e.g. invalid_grant.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code string, description string) error {
+	return &OAuthError{Code: code, Description: description}
+}
+
+type OAuthService struct {
+	OAuthRepository *repository.OAuthRepository
+	AuthService     *AuthService
+}
+
+func NewOAuthService(oauthRepository *repository.OAuthRepository, authService *AuthService) *OAuthService {
+	return &OAuthService{
+		OAuthRepository: oauthRepository,
+		AuthService:     authService,
+	}
+}
+
+// RegisterClient creates a client. The secret of a confidential client is
+// returned only here; auth-service keeps just its hash.
+func (s *OAuthService) RegisterClient(ctx context.Context, adminID uint, p *payload.CreateOAuthClientPayload) (*payload.CreateOAuthClientResponse, error) {
+	grantTypes := normalizeScopes(p.GrantTypes)
+
+	if slices.Contains(grantTypes, consts.OAuthGrantAuthorizationCode) && len(p.RedirectURIs) == 0 {
+		return nil, fmt.Errorf("%w: authorization_code needs a redirect uri", consts.ErrInvalidOAuthClient)
+	}
+
+	if slices.Contains(grantTypes, consts.OAuthGrantClientCredentials) && p.Public {
+		return nil, fmt.Errorf("%w: public clients cannot use client_credentials", consts.ErrInvalidOAuthClient)
+	}
+
+	for _, scope := range p.Scopes {
+		if scope != consts.OAuthScopeIntrospect && !slices.Contains(consts.OAuthScopes, scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q", consts.ErrInvalidOAuthClient, scope)
+		}
+	}
+
+	if slices.Contains(p.Scopes, consts.OAuthScopeIntrospect) && p.Public {
+		return nil, fmt.Errorf("%w: public clients cannot introspect tokens", consts.ErrInvalidOAuthClient)
+	}
+
+	for _, redirectURI := range p.RedirectURIs {
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || parsed.Fragment != "" {
+			return nil, fmt.Errorf("%w: redirect uri must not contain a fragment", consts.ErrInvalidOAuthClient)
+		}
+	}
+
+	secret, err := token.Generate()
+	if err != nil {
+		return nil, consts.ErrFailedCreateOAuthClient
+	}
+
+	id, err := token.Generate()
+	if err != nil {
+		return nil, consts.ErrFailedCreateOAuthClient
+	}
+
+	client := model.OAuthClient{
+		ID:           consts.OAuthClientIDPrefix + id,
+		Name:         p.Name,
+		RedirectURIs: p.RedirectURIs,
+		Scopes:       normalizeScopes(p.Scopes),
+		GrantTypes:   grantTypes,
+		Public:       p.Public,
+		CreatedBy:    &adminID,
+	}
+
+	if client.RedirectURIs == nil {
+		client.RedirectURIs = []string{}
+	}
+
+	if !p.Public {
+		secretHash := token.Hash(secret)
+		client.SecretHash = &secretHash
+	} else {
+		secret = ""
+	}
+
+	err = s.OAuthRepository.CreateClient(ctx, &client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payload.CreateOAuthClientResponse{
+		OAuthClient:  client,
+		ClientSecret: secret,
+	}, nil
+}
+
+func (s *OAuthService) ListClients(ctx context.Context) ([]model.OAuthClient, error) {
+	return s.OAuthRepository.ListClients(ctx)
+}
+
+func (s *OAuthService) RevokeClient(ctx context.Context, id string) error {
+	return s.OAuthRepository.RevokeClient(ctx, id)
+}
+
+// Authorize validates an authorization request for the signed-in user. If
+// the user already consented to every requested scope the code is issued
+// right away, otherwise the front end has to ask and call Decide.
+func (s *OAuthService) Authorize(ctx context.Context, userID uint, r *payload.AuthorizeRequest) (*payload.AuthorizeResponse, error) {
+	client, scopes, err := s.validateAuthorize(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	consented, err := s.OAuthRepository.GetConsent(ctx, userID, client.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &payload.AuthorizeResponse{
+		Client: payload.AuthorizeClient{ID: client.ID, Name: client.Name},
+		Scopes: scopes,
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(consented, scope) {
+			data.ConsentRequired = true
+			return data, nil
+		}
+	}
+
+	data.RedirectTo, err = s.issueCode(ctx, userID, client, scopes, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Decide records the user's answer to the consent prompt. Approving adds the
+// scopes to the stored consent and issues a code; denying sends the client
+// back an access_denied error.
+func (s *OAuthService) Decide(ctx context.Context, userID uint, r *payload.AuthorizeRequest, approve bool) (*payload.AuthorizeResponse, error) {
+	client, scopes, err := s.validateAuthorize(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &payload.AuthorizeResponse{
+		Client: payload.AuthorizeClient{ID: client.ID, Name: client.Name},
+		Scopes: scopes,
+	}
+
+	if !approve {
+		data.RedirectTo = redirectURL(r.RedirectURI, url.Values{
+			"error":             {"access_denied"},
+			"error_description": {"the user denied the request"},
+		}, r.State)
+		return data, nil
+	}
+
+	consented, err := s.OAuthRepository.GetConsent(ctx, userID, client.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.OAuthRepository.SaveConsent(ctx, userID, client.ID, normalizeScopes(append(consented, scopes...)))
+	if err != nil {
+		return nil, err
+	}
+
+	data.RedirectTo, err = s.issueCode(ctx, userID, client, scopes, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (s *OAuthService) validateAuthorize(ctx context.Context, r *payload.AuthorizeRequest) (*model.OAuthClient, []string, error) {
+	client, err := s.OAuthRepository.GetClient(ctx, r.ClientID)
+	if errors.Is(err, consts.ErrOAuthClientNotFound) {
+		return nil, nil, oauthError("invalid_request", "unknown client_id")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !slices.Contains(client.RedirectURIs, r.RedirectURI) {
+		return nil, nil, oauthError("invalid_request", "redirect_uri is not registered for the client")
+	}
+
+	if r.ResponseType != "code" {
+		return nil, nil, oauthError("unsupported_response_type", "only response_type=code is supported")
+	}
+
+	if !slices.Contains(client.GrantTypes, consts.OAuthGrantAuthorizationCode) {
+		return nil, nil, oauthError("unauthorized_client", "client may not use the authorization code grant")
+	}
+
+	if r.CodeChallengeMethod != consts.OAuthChallengeMethodS256 || len(r.CodeChallenge) < 43 || len(r.CodeChallenge) > 128 {
+		return nil, nil, oauthError("invalid_request", "a code_challenge with code_challenge_method=S256 is required")
+	}
+
+	scopes, err := requestedScopes(client, r.Scope)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return client, scopes, nil
+}
+
+func (s *OAuthService) issueCode(ctx context.Context, userID uint, client *model.OAuthClient, scopes []string, r *payload.AuthorizeRequest) (string, error) {
+	code, err := token.Generate()
+	if err != nil {
+		return "", consts.ErrFailedOAuthCode
+	}
+
+	err = s.OAuthRepository.CreateCode(ctx, &model.OAuthAuthorizationCode{
+		CodeHash:      token.Hash(code),
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   r.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: r.CodeChallenge,
+		ExpiresAt:     time.Now().Add(consts.OAuthCodeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return redirectURL(r.RedirectURI, url.Values{"code": {code}}, r.State), nil
+}
+
+// Token implements the token endpoint for every supported grant type.
+func (s *OAuthService) Token(ctx context.Context, r *payload.TokenRequest, info model.ClientInfo) (*payload.TokenResponse, error) {
+	client, err := s.authenticateClient(ctx, r.ClientID, r.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(client.GrantTypes, r.GrantType) {
+		return nil, oauthError("unauthorized_client", "client may not use grant type "+r.GrantType)
+	}
+
+	switch r.GrantType {
+	case consts.OAuthGrantAuthorizationCode:
+		return s.exchangeCode(ctx, client, r, info)
+	case consts.OAuthGrantRefreshToken:
+		return s.refresh(ctx, client, r, info)
+	case consts.OAuthGrantClientCredentials:
+		return s.clientCredentials(client, r)
+	}
+
+	return nil, oauthError("unsupported_grant_type", "unsupported grant_type")
+}
+
+// authenticateClient checks the client secret of confidential clients. Public
+// clients only identify themselves and are bound to the code by PKCE.
+func (s *OAuthService) authenticateClient(ctx context.Context, id string, secret string) (*model.OAuthClient, error) {
+	client, err := s.OAuthRepository.GetClient(ctx, id)
+	if errors.Is(err, consts.ErrOAuthClientNotFound) {
+		return nil, oauthError("invalid_client", "client authentication failed")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if client.SecretHash != nil && subtle.ConstantTimeCompare([]byte(token.Hash(secret)), []byte(*client.SecretHash)) != 1 {
+		return nil, oauthError("invalid_client", "client authentication failed")
+	}
+
+	return client, nil
+}
+
+func (s *OAuthService) exchangeCode(ctx context.Context, client *model.OAuthClient, r *payload.TokenRequest, info model.ClientInfo) (*payload.TokenResponse, error) {
+	code, err := s.OAuthRepository.ConsumeCode(ctx, token.Hash(r.Code))
+	if err != nil {
+		return nil, err
+	}
+
+	if code == nil || code.ClientID != client.ID {
+		return nil, oauthError("invalid_grant", "authorization code is invalid")
+	}
+
+	// A replayed code means it leaked, so the tokens issued for it go too.
+	if code.UsedAt != nil {
+		if code.SessionID != nil {
+			err = s.AuthService.SessionRepository.Revoke(ctx, code.UserID, *code.SessionID)
+			if err != nil && !errors.Is(err, consts.ErrSessionNotFound) {
+				return nil, err
+			}
+		}
+
+		return nil, oauthError("invalid_grant", "authorization code was already used")
+	}
+
+	if time.Now().After(code.ExpiresAt) {
+		return nil, oauthError("invalid_grant", "authorization code expired")
+	}
+
+	if code.RedirectURI != r.RedirectURI {
+		return nil, oauthError("invalid_grant", "redirect_uri does not match the authorization request")
+	}
+
+	if !verifyCodeChallenge(r.CodeVerifier, code.CodeChallenge) {
+		return nil, oauthError("invalid_grant", "code_verifier does not match the code challenge")
+	}
+
+	user, err := s.AuthService.AuthRepository.GetUserByID(ctx, code.UserID)
+	if errors.Is(err, consts.ErrUserNotFound) {
+		return nil, oauthError("invalid_grant", "authorization code is invalid")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.DisabledAt != nil {
+		return nil, oauthError("invalid_grant", consts.ErrUserDisabled.Error())
+	}
+
+	session := &model.Session{
+		ClientID:  &client.ID,
+		Scopes:    code.Scopes,
+		UserAgent: info.UserAgent,
+		IP:        info.IP,
+	}
+
+	data, err := s.AuthService.startSession(ctx, user, session)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.OAuthRepository.SetCodeSession(ctx, code.ID, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenResponse(client, data, code.Scopes), nil
+}
+
+func (s *OAuthService) refresh(ctx context.Context, client *model.OAuthClient, r *payload.TokenRequest, info model.ClientInfo) (*payload.TokenResponse, error) {
+	session, err := s.AuthService.SessionRepository.GetByRefreshToken(ctx, token.Hash(r.RefreshToken))
+	if errors.Is(err, consts.ErrSessionNotFound) {
+		return nil, oauthError("invalid_grant", consts.ErrRefreshTokenNotFound.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.AuthService.rotateSession(ctx, r.RefreshToken, client.ID, info)
+	switch {
+	case errors.Is(err, consts.ErrRefreshTokenNotFound),
+		errors.Is(err, consts.ErrRefreshTokenExpired),
+		errors.Is(err, consts.ErrRefreshTokenReused),
+		errors.Is(err, consts.ErrUserDisabled),
+		errors.Is(err, consts.ErrUserNotFound):
+		return nil, oauthError("invalid_grant", err.Error())
+	case err != nil:
+		return nil, err
+	}
+
+	return tokenResponse(client, data, session.Scopes), nil
+}
+
+// clientCredentials issues a token to the client itself. It has no user, no
+// session and no refresh token; the client simply asks again when it expires.
+func (s *OAuthService) clientCredentials(client *model.OAuthClient, r *payload.TokenRequest) (*payload.TokenResponse, error) {
+	if client.Public {
+		return nil, oauthError("unauthorized_client", "public clients cannot use client_credentials")
+	}
+
+	scopes, err := requestedScopes(client, r.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := s.AuthService.KeyService.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	scope := strings.Join(scopes, " ")
+
+	accessToken, err := jwt.GenerateToken(key, jwt.Claims{
+		Permissions: scopes,
+		ClientID:    client.ID,
+		Scope:       scope,
+	})
+	if err != nil {
+		return nil, consts.ErrGenerateToken
+	}
+
+	return &payload.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(consts.AccessTokenTTL.Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// Introspect reports the state of an access token to a client holding the
+// tokens:introspect scope. Anything that is not a valid, unrevoked token of
+// this service is simply inactive, as RFC 7662 requires.
+func (s *OAuthService) Introspect(ctx context.Context, clientID string, clientSecret string, tokenStr string) (*payload.IntrospectionResponse, error) {
+	caller, err := s.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if caller.Public || !slices.Contains(caller.Scopes, consts.OAuthScopeIntrospect) {
+		return nil, oauthError("insufficient_scope", "client may not introspect tokens")
+	}
+
+	if tokenStr == "" {
+		return nil, oauthError("invalid_request", "token is required")
+	}
+
+	claims, err := jwt.ParseToken(tokenStr, s.AuthService.KeyService.Lookup)
+	if err != nil {
+		return &payload.IntrospectionResponse{Active: false}, nil
+	}
+
+	err = s.AuthService.checkRevoked(ctx, claims)
+	if errors.Is(err, consts.ErrTokenRevoked) || errors.Is(err, consts.ErrSessionRevoked) {
+		return &payload.IntrospectionResponse{Active: false, Revoked: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.IsOAuth() {
+		_, err = s.OAuthRepository.GetClient(ctx, claims.ClientID)
+		if errors.Is(err, consts.ErrOAuthClientNotFound) {
+			return &payload.IntrospectionResponse{Active: false, Revoked: true}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data := &payload.IntrospectionResponse{
+		Active:      true,
+		Subject:     claims.ClientID,
+		Role:        claims.Role,
+		Scope:       claims.Scope,
+		Permissions: claims.Permissions,
+		ClientID:    claims.ClientID,
+		SessionID:   claims.SessionID,
+		TokenType:   "Bearer",
+		ExpiresAt:   claims.ExpiresAt.Unix(),
+		IssuedAt:    claims.IssuedAt.Unix(),
+		JTI:         claims.ID,
+	}
+
+	if data.Scope == "" {
+		data.Scope = strings.Join(claims.Permissions, " ")
+	}
+
+	if claims.UserID != 0 {
+		user, err := s.AuthService.AuthRepository.GetUserByID(ctx, claims.UserID)
+		if errors.Is(err, consts.ErrUserNotFound) {
+			return &payload.IntrospectionResponse{Active: false, Revoked: true}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data.Subject = strconv.FormatUint(uint64(user.ID), 10)
+		data.Username = user.UserName
+	}
+
+	return data, nil
+}
+
+func tokenResponse(client *model.OAuthClient, data *payload.AuthLoginResponse, scopes []string) *payload.TokenResponse {
+	response := &payload.TokenResponse{
+		AccessToken: data.Token,
+		TokenType:   "Bearer",
+		ExpiresIn:   data.ExpiresIn,
+		Scope:       strings.Join(scopes, " "),
+	}
+
+	if slices.Contains(client.GrantTypes, consts.OAuthGrantRefreshToken) {
+		response.RefreshToken = data.RefreshToken
+	}
+
+	return response
+}
+
+// requestedScopes parses a space-separated scope parameter. An empty one
+// means every scope the client may put into tokens.
+func requestedScopes(client *model.OAuthClient, scope string) ([]string, error) {
+	grantable := slices.DeleteFunc(slices.Clone(client.Scopes), func(scope string) bool {
+		return scope == consts.OAuthScopeIntrospect
+	})
+
+	scopes := strings.Fields(scope)
+	if len(scopes) == 0 {
+		return grantable, nil
+	}
+
+	for _, requested := range scopes {
+		if !slices.Contains(grantable, requested) {
+			return nil, oauthError("invalid_scope", "scope "+requested+" is not allowed for the client")
+		}
+	}
+
+	return normalizeScopes(scopes), nil
+}
+
+func normalizeScopes(scopes []string) []string {
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	return slices.Compact(scopes)
+}
+
+// verifyCodeChallenge checks a PKCE verifier against its S256 challenge.
+func verifyCodeChallenge(verifier string, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+func redirectURL(redirectURI string, params url.Values, state string) string {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := target.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+
+	if state != "" {
+		query.Set("state", state)
+	}
+
+	target.RawQuery = query.Encode()
+
+	return target.String()
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"auth-service/internal/payload"
+	"auth-service/internal/repository"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/mail"
+	"auth-service/pkg/password"
+	"auth-service/pkg/token"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+)
+
+type PasswordService struct {
+	AuthRepository          *repository.AuthRepository
+	PasswordResetRepository *repository.PasswordResetRepository
+	RevocationRepository    *repository.RevocationRepository
+	ThrottleService         *ThrottleService
+	MailSender              mail.Sender
+	PasswordPolicy          *password.Policy
+	resetURL                string
+}
+
+func NewPasswordService(
+	authRepository *repository.AuthRepository,
+	passwordResetRepository *repository.PasswordResetRepository,
+	revocationRepository *repository.RevocationRepository,
+	throttleService *ThrottleService,
+	mailSender mail.Sender,
+	passwordPolicy *password.Policy,
+	resetURL string,
+) *PasswordService {
+	return &PasswordService{
+		AuthRepository:          authRepository,
+		PasswordResetRepository: passwordResetRepository,
+		RevocationRepository:    revocationRepository,
+		ThrottleService:         throttleService,
+		MailSender:              mailSender,
+		PasswordPolicy:          passwordPolicy,
+		resetURL:                resetURL,
+	}
+}
+
+// Change sets a new password after checking the current one and signs the
+// user out everywhere, since the old password may have been compromised.
+// Wrong current passwords count as failed logins, so a stolen access token
+// cannot be used to guess the password.
+func (s *PasswordService) Change(ctx context.Context, userID uint, ip string, p *payload.ChangePasswordPayload) error {
+	user, err := s.AuthRepository.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = s.ThrottleService.Check(ctx, user.UserName, ip)
+	if err != nil {
+		return err
+	}
+
+	ok, _, err := password.Verify(p.CurrentPassword, user.PasswordHash)
+	if err != nil || !ok {
+		err = s.ThrottleService.RecordFailure(ctx, user.UserName, ip)
+		if err != nil {
+			return err
+		}
+		return consts.ErrInvalidCredentials
+	}
+
+	return s.setPassword(ctx, user.ID, p.NewPassword)
+}
+
+// RequestReset mails a reset link when the email belongs to an active user.
+// It reports success either way so the endpoint cannot be used to find out
+// which emails are registered.
+func (s *PasswordService) RequestReset(ctx context.Context, p *payload.RequestPasswordResetPayload) error {
+	user, err := s.AuthRepository.GetUserByEmail(ctx, p.Email)
+	if errors.Is(err, consts.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.DisabledAt != nil || user.Email == nil {
+		return nil
+	}
+
+	resetToken, err := token.Generate()
+	if err != nil {
+		return consts.ErrFailedCreateReset
+	}
+
+	err = s.PasswordResetRepository.Create(ctx, user.ID, token.Hash(resetToken), time.Now().Add(consts.PasswordResetTTL))
+	if err != nil {
+		return err
+	}
+
+	link := s.resetURL + "?token=" + url.QueryEscape(resetToken)
+
+	err = s.MailSender.Send(ctx, mail.Message{
+		To:      *user.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nuse the link below to set a new password. It is valid for %s and can be used once.\n\n%s\n\nIf you did not request a reset, ignore this message.",
+			user.UserName,
+			consts.PasswordResetTTL,
+			link,
+		),
+	})
+	if err != nil {
+		slog.ErrorContext(ctx, "Send password reset mail error", "error", err)
+		return consts.ErrFailedSendMail
+	}
+
+	return nil
+}
+
+func (s *PasswordService) ConfirmReset(ctx context.Context, p *payload.ConfirmPasswordResetPayload) error {
+	// Checked before the token is consumed so a rejected password does not
+	// burn the reset link.
+	err := s.PasswordPolicy.Check(p.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	userID, err := s.PasswordResetRepository.Consume(ctx, token.Hash(p.Token))
+	if err != nil {
+		return err
+	}
+
+	err = s.setPassword(ctx, userID, p.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	err = s.PasswordResetRepository.InvalidateForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.AuthRepository.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.ThrottleService.Reset(ctx, user.UserName)
+}
+
+func (s *PasswordService) setPassword(ctx context.Context, userID uint, plainPassword string) error {
+	err := s.PasswordPolicy.Check(plainPassword)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := password.Hash(plainPassword)
+	if err != nil {
+		return consts.ErrFailedHashedPassword
+	}
+
+	err = s.AuthRepository.UpdatePassword(ctx, userID, hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	return s.RevocationRepository.RevokeAllForUser(ctx, userID)
+}
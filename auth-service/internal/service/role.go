@@ -0,0 +1,35 @@
+package service
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"auth-service/pkg/consts"
+	"context"
+)
+
+type RoleService struct {
+	RoleRepository *repository.RoleRepository
+}
+
+func NewRoleService(roleRepository *repository.RoleRepository) *RoleService {
+	return &RoleService{
+		RoleRepository: roleRepository,
+	}
+}
+
+func (s *RoleService) List(ctx context.Context) ([]model.Role, error) {
+	return s.RoleRepository.List(ctx)
+}
+
+func (s *RoleService) Validate(ctx context.Context, name string) error {
+	exists, err := s.RoleRepository.Exists(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return consts.ErrRoleNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"context"
+)
+
+type SessionService struct {
+	SessionRepository *repository.SessionRepository
+}
+
+func NewSessionService(sessionRepository *repository.SessionRepository) *SessionService {
+	return &SessionService{
+		SessionRepository: sessionRepository,
+	}
+}
+
+// List returns the user's active sessions and marks the one currentID
+// belongs to, so clients can tell "this device" apart from the others.
+func (s *SessionService) List(ctx context.Context, userID uint, currentID string) ([]model.Session, error) {
+	sessions, err := s.SessionRepository.ListActive(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	return sessions, nil
+}
+
+func (s *SessionService) Revoke(ctx context.Context, userID uint, id string) error {
+	return s.SessionRepository.Revoke(ctx, userID, id)
+}
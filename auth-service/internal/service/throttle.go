@@ -0,0 +1,134 @@
+package service
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/repository"
+	"auth-service/pkg/consts"
+	"context"
+	"time"
+)
+
+type throttleLimit struct {
+	prefix     string
+	delayAfter int
+	lockAfter  int
+}
+
+// A single IP legitimately serves many users (NAT, offices), so it gets
+// more headroom than a single username.
+var (
+	usernameLimit = throttleLimit{prefix: "user:", delayAfter: 3, lockAfter: 10}
+	ipLimit       = throttleLimit{prefix: "ip:", delayAfter: 10, lockAfter: 50}
+)
+
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return consts.ErrTooManyAttempts.Error()
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return consts.ErrTooManyAttempts
+}
+
+type ThrottleService struct {
+	LoginAttemptRepository *repository.LoginAttemptRepository
+}
+
+func NewThrottleService(loginAttemptRepository *repository.LoginAttemptRepository) *ThrottleService {
+	return &ThrottleService{
+		LoginAttemptRepository: loginAttemptRepository,
+	}
+}
+
+// Check returns a *ThrottledError when either the username or the client IP
+// is locked out or still has to wait after its last failure.
+func (s *ThrottleService) Check(ctx context.Context, username string, ip string) error {
+	var retryAfter time.Duration
+
+	for _, check := range s.keys(username, ip) {
+		attempt, err := s.LoginAttemptRepository.Get(ctx, check.key)
+		if err != nil {
+			return err
+		}
+
+		wait := check.limit.wait(attempt)
+		if wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &ThrottledError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+func (s *ThrottleService) RecordFailure(ctx context.Context, username string, ip string) error {
+	windowStart := time.Now().Add(-consts.LoginAttemptWindow)
+
+	for _, check := range s.keys(username, ip) {
+		attempt, err := s.LoginAttemptRepository.RecordFailure(ctx, check.key, windowStart)
+		if err != nil {
+			return err
+		}
+
+		if attempt.Failures >= check.limit.lockAfter {
+			err = s.LoginAttemptRepository.Lock(ctx, check.key, time.Now().Add(consts.LoginLockDuration))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Reset clears the username counter after a successful login. The IP
+// counter is left alone so an attacker cannot reset it by logging into an
+// account of their own.
+func (s *ThrottleService) Reset(ctx context.Context, username string) error {
+	return s.LoginAttemptRepository.Reset(ctx, usernameLimit.prefix+username)
+}
+
+type throttleKey struct {
+	key   string
+	limit throttleLimit
+}
+
+func (s *ThrottleService) keys(username string, ip string) []throttleKey {
+	keys := []throttleKey{{key: usernameLimit.prefix + username, limit: usernameLimit}}
+	if ip != "" {
+		keys = append(keys, throttleKey{key: ipLimit.prefix + ip, limit: ipLimit})
+	}
+	return keys
+}
+
+// wait returns how long the caller has to wait before the next attempt: the
+// remaining lockout, or a delay that doubles with every failure past
+// delayAfter.
+func (l throttleLimit) wait(attempt *model.LoginAttempt) time.Duration {
+	if attempt == nil {
+		return 0
+	}
+
+	now := time.Now()
+
+	if attempt.LockedUntil != nil && now.Before(*attempt.LockedUntil) {
+		return attempt.LockedUntil.Sub(now)
+	}
+
+	if now.Sub(attempt.LastFailureAt) > consts.LoginAttemptWindow || attempt.Failures < l.delayAfter {
+		return 0
+	}
+
+	delay := consts.LoginMaxDelay
+	if shift := attempt.Failures - l.delayAfter; shift < 6 {
+		delay = min(time.Second<<shift, consts.LoginMaxDelay)
+	}
+
+	return max(attempt.LastFailureAt.Add(delay).Sub(now), 0)
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"auth-service/internal/model"
+	"auth-service/internal/payload"
+	"auth-service/internal/repository"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/token"
+	"auth-service/pkg/totp"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+)
+
+type TOTPService struct {
+	AuthRepository *repository.AuthRepository
+	TOTPRepository *repository.TOTPRepository
+}
+
+func NewTOTPService(authRepository *repository.AuthRepository, totpRepository *repository.TOTPRepository) *TOTPService {
+	return &TOTPService{
+		AuthRepository: authRepository,
+		TOTPRepository: totpRepository,
+	}
+}
+
+func (s *TOTPService) Enroll(ctx context.Context, userID uint) (*payload.TOTPEnrollResponse, error) {
+	user, err := s.AuthRepository.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, consts.ErrFailedTOTP
+	}
+
+	err = s.TOTPRepository.SavePending(ctx, userID, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payload.TOTPEnrollResponse{
+		Secret: secret,
+		URI:    totp.URI(consts.TOTPIssuer, user.UserName, secret),
+	}, nil
+}
+
+// Confirm enables TOTP once the user proves the authenticator works and
+// returns freshly generated recovery codes. They are shown only this once.
+func (s *TOTPService) Confirm(ctx context.Context, userID uint, code string) ([]string, error) {
+	t, err := s.TOTPRepository.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.EnabledAt != nil {
+		return nil, consts.ErrTOTPAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(t.Secret, code, time.Now())
+	if !ok {
+		return nil, consts.ErrInvalidTOTPCode
+	}
+
+	codes := make([]string, 0, consts.RecoveryCodeCount)
+	hashes := make([]string, 0, consts.RecoveryCodeCount)
+
+	for range consts.RecoveryCodeCount {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, consts.ErrFailedTOTP
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, token.Hash(normalizeRecoveryCode(code)))
+	}
+
+	err = s.TOTPRepository.Enable(ctx, userID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (s *TOTPService) Disable(ctx context.Context, userID uint, code string) error {
+	err := s.Verify(ctx, userID, code)
+	if err != nil {
+		return err
+	}
+
+	return s.TOTPRepository.Delete(ctx, userID)
+}
+
+// Reset removes TOTP without a code, for admins helping a user who lost
+// both the authenticator and the recovery codes.
+func (s *TOTPService) Reset(ctx context.Context, userID uint) error {
+	return s.TOTPRepository.Delete(ctx, userID)
+}
+
+func (s *TOTPService) IsEnabled(ctx context.Context, userID uint) (bool, error) {
+	t, err := s.TOTPRepository.GetByUserID(ctx, userID)
+	if errors.Is(err, consts.ErrTOTPNotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return t.EnabledAt != nil, nil
+}
+
+// Verify accepts either a current TOTP code that was not used before or an
+// unused recovery code.
+func (s *TOTPService) Verify(ctx context.Context, userID uint, code string) error {
+	t, err := s.TOTPRepository.GetByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if t.EnabledAt == nil {
+		return consts.ErrTOTPNotEnrolled
+	}
+
+	step, ok := totp.Validate(t.Secret, code, time.Now())
+	if ok {
+		fresh, err := s.TOTPRepository.UseStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if fresh {
+			return nil
+		}
+		return consts.ErrInvalidTOTPCode
+	}
+
+	used, err := s.TOTPRepository.UseRecoveryCode(ctx, userID, token.Hash(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return consts.ErrInvalidTOTPCode
+	}
+
+	return nil
+}
+
+func (s *TOTPService) CreateChallenge(ctx context.Context, userID uint) (string, error) {
+	challengeToken, err := token.Generate()
+	if err != nil {
+		return "", consts.ErrFailedTOTP
+	}
+
+	err = s.TOTPRepository.CreateChallenge(ctx, &model.MFAChallenge{
+		UserID:    userID,
+		TokenHash: token.Hash(challengeToken),
+		ExpiresAt: time.Now().Add(consts.MFAChallengeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return challengeToken, nil
+}
+
+// ChallengeUser returns the user a pending login challenge was issued for.
+func (s *TOTPService) ChallengeUser(ctx context.Context, challengeToken string) (uint, error) {
+	challenge, err := s.TOTPRepository.GetChallenge(ctx, token.Hash(challengeToken))
+	if err != nil {
+		return 0, err
+	}
+
+	return challenge.UserID, nil
+}
+
+// CompleteChallenge verifies the second factor for a login challenge and
+// returns the user it was issued for.
+func (s *TOTPService) CompleteChallenge(ctx context.Context, challengeToken string, code string) (uint, error) {
+	challenge, err := s.TOTPRepository.GetChallenge(ctx, token.Hash(challengeToken))
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.Verify(ctx, challenge.UserID, code)
+	if errors.Is(err, consts.ErrInvalidTOTPCode) {
+		failErr := s.TOTPRepository.FailChallenge(ctx, challenge.ID, consts.MFAChallengeMaxAttempt)
+		if failErr != nil {
+			return 0, failErr
+		}
+		return 0, err
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	consumed, err := s.TOTPRepository.ConsumeChallenge(ctx, challenge.ID)
+	if err != nil {
+		return 0, err
+	}
+	if !consumed {
+		return 0, consts.ErrInvalidChallenge
+	}
+
+	return challenge.UserID, nil
+}
+
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 5)
+
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(b))
+
+	return code[:4] + "-" + code[4:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"auth-service/internal/postgres"
+	"auth-service/internal/repository"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/token"
+	"auth-service/pkg/totp"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testUserID = 7
+
+// totpStore answers the few statements TOTPRepository.Verify issues for a
+// single enrolled user, enough to exercise the single use rules without a
+// database.
+type totpStore struct {
+	secret        string
+	lastUsedStep  int64
+	recoveryCodes map[string]bool // hash -> used
+}
+
+func (s *totpStore) Connect(context.Context) (driver.Conn, error) { return &totpConn{s}, nil }
+func (s *totpStore) Driver() driver.Driver                        { return nil }
+
+type totpConn struct {
+	store *totpStore
+}
+
+func (c *totpConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *totpConn) Close() error                        { return nil }
+func (c *totpConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (c *totpConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.HasPrefix(query, "SELECT user_id, secret, enabled_at, last_used_step, created_at FROM user_totp") {
+		return nil, errors.New("unexpected query: " + query)
+	}
+	if args[0].Value != int64(testUserID) {
+		return &totpRows{}, nil
+	}
+
+	enabledAt := time.Now().Add(-time.Hour)
+
+	return &totpRows{values: []driver.Value{
+		int64(testUserID), c.store.secret, enabledAt, c.store.lastUsedStep, enabledAt,
+	}}, nil
+}
+
+func (c *totpConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	switch {
+	case strings.HasPrefix(query, "UPDATE user_totp SET last_used_step"):
+		step := args[0].Value.(int64)
+		if step <= c.store.lastUsedStep {
+			return driver.RowsAffected(0), nil
+		}
+		c.store.lastUsedStep = step
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, "UPDATE totp_recovery_codes SET used_at"):
+		hash := args[1].Value.(string)
+		used, ok := c.store.recoveryCodes[hash]
+		if !ok || used {
+			return driver.RowsAffected(0), nil
+		}
+		c.store.recoveryCodes[hash] = true
+		return driver.RowsAffected(1), nil
+	}
+
+	return nil, errors.New("unexpected statement: " + query)
+}
+
+type totpRows struct {
+	values []driver.Value
+	done   bool
+}
+
+func (r *totpRows) Columns() []string {
+	return []string{"user_id", "secret", "enabled_at", "last_used_step", "created_at"}
+}
+
+func (r *totpRows) Close() error { return nil }
+
+func (r *totpRows) Next(dest []driver.Value) error {
+	if r.values == nil || r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.values)
+	return nil
+}
+
+func newTestTOTPService(t *testing.T, store *totpStore) *TOTPService {
+	t.Helper()
+
+	db := sql.OpenDB(store)
+	t.Cleanup(func() { db.Close() })
+
+	return NewTOTPService(nil, repository.NewTOTPRepository(&postgres.Db{DB: db}))
+}
+
+func TestVerifyRecoveryCodeSingleUse(t *testing.T) {
+	store := &totpStore{
+		secret:        "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		recoveryCodes: map[string]bool{token.Hash("abcdefgh"): false},
+	}
+	s := newTestTOTPService(t, store)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		code string
+		want error
+	}{
+		{name: "first use", code: "abcd-efgh", want: nil},
+		{name: "reused", code: "abcd-efgh", want: consts.ErrInvalidTOTPCode},
+		{name: "reused in another spelling", code: " ABCDEFGH ", want: consts.ErrInvalidTOTPCode},
+		{name: "unknown", code: "zzzz-zzzz", want: consts.ErrInvalidTOTPCode},
+	}
+
+	for _, tt := range tests {
+		err := s.Verify(ctx, testUserID, tt.code)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: Verify = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTPCodeSingleUse(t *testing.T) {
+	store := &totpStore{
+		secret:        "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+		recoveryCodes: map[string]bool{},
+	}
+	s := newTestTOTPService(t, store)
+	ctx := context.Background()
+
+	step := totp.Step(time.Now())
+	code, err := totp.Code(store.secret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Verify(ctx, testUserID, code)
+	if err != nil {
+		t.Fatalf("first use: Verify = %v", err)
+	}
+
+	err = s.Verify(ctx, testUserID, code)
+	if !errors.Is(err, consts.ErrInvalidTOTPCode) {
+		t.Fatalf("replay: Verify = %v, want %v", err, consts.ErrInvalidTOTPCode)
+	}
+
+	// An older step inside the skew window is a replay too once a later one
+	// was accepted.
+	previous, err := totp.Code(store.secret, step-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Verify(ctx, testUserID, previous)
+	if !errors.Is(err, consts.ErrInvalidTOTPCode) {
+		t.Fatalf("older step: Verify = %v, want %v", err, consts.ErrInvalidTOTPCode)
+	}
+}
+
+func TestVerifyNotEnrolled(t *testing.T) {
+	s := newTestTOTPService(t, &totpStore{secret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"})
+
+	err := s.Verify(context.Background(), testUserID+1, "123456")
+	if !errors.Is(err, consts.ErrTOTPNotEnrolled) {
+		t.Fatalf("Verify = %v, want %v", err, consts.ErrTOTPNotEnrolled)
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"auth-service/internal/payload"
+	"auth-service/internal/repository"
+	"auth-service/pkg/consts"
+	"context"
+)
+
+type UserService struct {
+	AuthRepository       *repository.AuthRepository
+	UserRepository       *repository.UserRepository
+	RevocationRepository *repository.RevocationRepository
+	ThrottleService      *ThrottleService
+	RoleService          *RoleService
+}
+
+func NewUserService(
+	authRepository *repository.AuthRepository,
+	userRepository *repository.UserRepository,
+	revocationRepository *repository.RevocationRepository,
+	throttleService *ThrottleService,
+	roleService *RoleService,
+) *UserService {
+	return &UserService{
+		AuthRepository:       authRepository,
+		UserRepository:       userRepository,
+		RevocationRepository: revocationRepository,
+		ThrottleService:      throttleService,
+		RoleService:          roleService,
+	}
+}
+
+func (s *UserService) List(ctx context.Context, search string, page uint64, limit uint64) (*payload.ListUsersResponse, error) {
+	if page == 0 {
+		page = 1
+	}
+	if limit == 0 {
+		limit = consts.DefaultPageLimit
+	}
+	if limit > consts.MaxPageLimit {
+		limit = consts.MaxPageLimit
+	}
+
+	users, total, err := s.UserRepository.List(ctx, search, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &payload.ListUsersResponse{
+		Data:  users,
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}, nil
+}
+
+// UpdateRole changes the user's role and revokes the tokens that still carry
+// the old one.
+func (s *UserService) UpdateRole(ctx context.Context, adminID uint, id uint, role string) error {
+	if adminID == id {
+		return consts.ErrCannotModifySelf
+	}
+
+	err := s.RoleService.Validate(ctx, role)
+	if err != nil {
+		return err
+	}
+
+	err = s.UserRepository.UpdateRole(ctx, id, role)
+	if err != nil {
+		return err
+	}
+
+	return s.RevocationRepository.RevokeAllForUser(ctx, id)
+}
+
+func (s *UserService) SetDisabled(ctx context.Context, adminID uint, id uint, disabled bool) error {
+	if adminID == id {
+		return consts.ErrCannotModifySelf
+	}
+
+	err := s.UserRepository.SetDisabled(ctx, id, disabled)
+	if err != nil {
+		return err
+	}
+
+	if !disabled {
+		return nil
+	}
+
+	return s.RevocationRepository.RevokeAllForUser(ctx, id)
+}
+
+func (s *UserService) Delete(ctx context.Context, adminID uint, id uint) error {
+	if adminID == id {
+		return consts.ErrCannotModifySelf
+	}
+
+	return s.UserRepository.Delete(ctx, id)
+}
+
+func (s *UserService) RevokeTokens(ctx context.Context, id uint) error {
+	return s.RevocationRepository.RevokeAllForUser(ctx, id)
+}
+
+func (s *UserService) Unlock(ctx context.Context, id uint) error {
+	user, err := s.AuthRepository.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.ThrottleService.Reset(ctx, user.UserName)
+}
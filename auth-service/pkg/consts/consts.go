@@ -1,12 +1,170 @@
 package consts
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
+
+const (
+	AccessTokenTTL   = 15 * time.Minute
+	RefreshTokenTTL  = 30 * 24 * time.Hour
+	InvitationTTL    = 72 * time.Hour
+	MFAChallengeTTL  = 5 * time.Minute
+	PasswordResetTTL = time.Hour
+	OAuthCodeTTL     = 5 * time.Minute
+
+	DefaultAccountDeletionDelay = 14 * 24 * time.Hour
+
+	// A new signing key is published this long before it signs tokens. The
+	// minimum matches how often the gateway may refetch the JWKS for an
+	// unknown kid.
+	DefaultKeyPrePublishPeriod = time.Minute
+	MinKeyPrePublishPeriod     = 10 * time.Second
+)
+
+const (
+	TOTPIssuer             = "MovieLibrary"
+	RecoveryCodeCount      = 10
+	MFAChallengeMaxAttempt = 5
+)
+
+const (
+	LoginAttemptWindow = 15 * time.Minute
+	LoginLockDuration  = 15 * time.Minute
+	LoginMaxDelay      = time.Minute
+)
+
+const (
+	DefaultPasswordMinLength = 10
+	PasswordMaxLength        = 256
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+const (
+	RoleUser   = "user"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+const (
+	PermMoviesRead   = "movies:read"
+	PermMoviesWrite  = "movies:write"
+	PermMoviesDelete = "movies:delete"
+	PermActorsRead   = "actors:read"
+	PermActorsWrite  = "actors:write"
+	PermActorsDelete = "actors:delete"
+	PermUsersManage  = "users:manage"
+	PermKeysManage   = "keys:manage"
+)
+
+const (
+	APIKeyPrefix     = "mlk_"
+	APIKeyScopeRead  = "read"
+	APIKeyScopeWrite = "write"
+)
+
+const (
+	OAuthClientIDPrefix = "mlc_"
+
+	OAuthGrantAuthorizationCode = "authorization_code"
+	OAuthGrantClientCredentials = "client_credentials"
+	OAuthGrantRefreshToken      = "refresh_token"
+
+	OAuthChallengeMethodS256 = "S256"
+
+	// OAuthScopeIntrospect lets a confidential client call /introspect. It is
+	// never put into access tokens.
+	OAuthScopeIntrospect = "tokens:introspect"
+)
+
+// OAuthScopes are the permissions a third-party client may be granted. Account
+// and key administration stay with first-party tokens.
+var OAuthScopes = []string{
+	PermMoviesRead,
+	PermMoviesWrite,
+	PermMoviesDelete,
+	PermActorsRead,
+	PermActorsWrite,
+	PermActorsDelete,
+}
 
 var (
 	ErrFailedToBuildSQL        = errors.New("failed to build SQL query")
 	ErrFailedCreateUser        = errors.New("failed to create user")
 	ErrFailedHashedPassword    = errors.New("failed hashed password")
 	ErrFailedGetUserByUserName = errors.New("failed get user by username")
+	ErrFailedGetUserByID       = errors.New("failed get user by id")
 	ErrInvalidCredentials      = errors.New("invalid credentials")
 	ErrGenerateToken           = errors.New("error generate jwt token")
+	ErrFailedToBeginTx         = errors.New("failed to begin transaction")
+	ErrFailedToCommitTx        = errors.New("failed to commit transaction")
+	ErrFailedCreateRefresh     = errors.New("failed to create refresh token")
+	ErrFailedRotateRefresh     = errors.New("failed to rotate refresh token")
+	ErrRefreshTokenNotFound    = errors.New("refresh token not found")
+	ErrRefreshTokenExpired     = errors.New("refresh token expired")
+	ErrRefreshTokenReused      = errors.New("refresh token reuse detected, session revoked")
+	ErrInvalidToken            = errors.New("invalid token")
+	ErrTokenRevoked            = errors.New("token revoked")
+	ErrMissingAuthHeader       = errors.New("missing auth header")
+	ErrAccessDenied            = errors.New("access denied")
+	ErrFailedRevokeToken       = errors.New("failed to revoke token")
+	ErrFailedCheckRevocation   = errors.New("failed to check token revocation")
+	ErrUserNotFound            = errors.New("user not found")
+	ErrFailedCheckAdmin        = errors.New("failed to check admin existence")
+	ErrFailedCreateInvitation  = errors.New("failed to create invitation")
+	ErrFailedRedeemInvitation  = errors.New("failed to redeem invitation")
+	ErrInvitationInvalid       = errors.New("invitation code is invalid, expired or already used")
+	ErrUserDisabled            = errors.New("user account is disabled")
+	ErrFailedListUsers         = errors.New("failed to list users")
+	ErrFailedUpdateUser        = errors.New("failed to update user")
+	ErrFailedDeleteUser        = errors.New("failed to delete user")
+	ErrCannotModifySelf        = errors.New("admins cannot change, disable or delete their own account")
+	ErrLastAdmin               = errors.New("the last active admin cannot be demoted, disabled or deleted")
+	ErrFailedLoadSigningKeys   = errors.New("failed to load signing keys")
+	ErrFailedRotateSigningKey  = errors.New("failed to rotate signing key")
+	ErrNoSigningKey            = errors.New("no active signing key")
+	ErrTooManyAttempts         = errors.New("too many failed login attempts, try again later")
+	ErrFailedTrackAttempts     = errors.New("failed to track login attempts")
+	ErrFailedTOTP              = errors.New("failed to process two-factor authentication")
+	ErrTOTPNotEnrolled         = errors.New("two-factor authentication is not enrolled")
+	ErrTOTPAlreadyEnabled      = errors.New("two-factor authentication is already enabled")
+	ErrInvalidTOTPCode         = errors.New("invalid two-factor code")
+	ErrInvalidChallenge        = errors.New("invalid or expired login challenge")
+	ErrFailedGetUserByEmail    = errors.New("failed get user by email")
+	ErrFailedUpdatePassword    = errors.New("failed to update password")
+	ErrFailedCreateAPIKey      = errors.New("failed to create api key")
+	ErrFailedListAPIKeys       = errors.New("failed to list api keys")
+	ErrFailedRevokeAPIKey      = errors.New("failed to revoke api key")
+	ErrAPIKeyNotFound          = errors.New("api key not found")
+	ErrNotServiceAccount       = errors.New("user is not a service account")
+	ErrRoleNotFound            = errors.New("role not found")
+	ErrFailedListRoles         = errors.New("failed to list roles")
+	ErrWeakPassword            = errors.New("password does not meet the password policy")
+	ErrFailedCreateSession     = errors.New("failed to create session")
+	ErrFailedUpdateSession     = errors.New("failed to update session")
+	ErrFailedListSessions      = errors.New("failed to list sessions")
+	ErrFailedRevokeSession     = errors.New("failed to revoke session")
+	ErrSessionNotFound         = errors.New("session not found")
+	ErrSessionRevoked          = errors.New("session revoked")
+	ErrFailedCreateReset       = errors.New("failed to create password reset")
+	ErrInvalidResetToken       = errors.New("password reset token is invalid, expired or already used")
+	ErrFailedSendMail          = errors.New("failed to send mail")
+	ErrFailedCreateOAuthClient = errors.New("failed to create oauth client")
+	ErrFailedListOAuthClients  = errors.New("failed to list oauth clients")
+	ErrFailedRevokeOAuthClient = errors.New("failed to revoke oauth client")
+	ErrFailedGetOAuthClient    = errors.New("failed to get oauth client")
+	ErrOAuthClientNotFound     = errors.New("oauth client not found")
+	ErrInvalidOAuthClient      = errors.New("oauth client is missing a redirect uri or grant type")
+	ErrFailedOAuthConsent      = errors.New("failed to process oauth consent")
+	ErrFailedOAuthCode         = errors.New("failed to process authorization code")
+	ErrOAuthTokenNotAllowed    = errors.New("oauth tokens cannot be used for this endpoint")
+	ErrFailedExportAccount     = errors.New("failed to export account data")
+	ErrFailedScheduleDeletion  = errors.New("failed to schedule account deletion")
+	ErrDeletionNotScheduled    = errors.New("account deletion is not scheduled")
+	ErrFailedPurgeAccounts     = errors.New("failed to delete scheduled accounts")
+	ErrFailedDeleteExpired     = errors.New("failed to delete expired records")
 )
@@ -0,0 +1,83 @@
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// encryptedPrefix marks private keys sealed by a KeyCipher, anything else in
+// the column is a PEM stored before encryption was introduced.
+const encryptedPrefix = "enc:v1:"
+
+var ErrInvalidEncryptionKey = errors.New("signing key encryption key must be 32 bytes, base64 encoded")
+
+// KeyCipher seals private keys with AES-256-GCM before they are stored, so a
+// dump or a reader of the database does not get usable signing keys. The key
+// id is bound as additional data: a sealed key copied to another row does not
+// open.
+type KeyCipher struct {
+	aead cipher.AEAD
+}
+
+// NewKeyCipher takes the base64 encoded 32 byte key.
+func NewKeyCipher(encoded string) (*KeyCipher, error) {
+	secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(secret) != 32 {
+		return nil, ErrInvalidEncryptionKey
+	}
+
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyCipher{aead: aead}, nil
+}
+
+func (c *KeyCipher) Seal(kid string, privateKeyPEM string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(privateKeyPEM), []byte(kid))
+
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (c *KeyCipher) Open(kid string, stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, encryptedPrefix)
+	if !ok {
+		return "", errors.New("private key is not encrypted")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", errors.New("malformed encrypted private key")
+	}
+
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+
+	plain, err := c.aead.Open(nil, nonce, ciphertext, []byte(kid))
+	if err != nil {
+		return "", err
+	}
+
+	return string(plain), nil
+}
+
+// IsSealed reports whether stored was produced by Seal.
+func IsSealed(stored string) bool {
+	return strings.HasPrefix(stored, encryptedPrefix)
+}
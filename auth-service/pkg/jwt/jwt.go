@@ -1,23 +1,85 @@
 package jwt
 
 import (
-	"os"
+	"auth-service/pkg/consts"
+	"auth-service/pkg/token"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateToken(userID uint, role string) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
+type Claims struct {
+	UserID      uint     `json:"userID"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	SessionID   string   `json:"sid,omitempty"`
+	ClientID    string   `json:"client_id,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (c *Claims) HasPermission(permission string) bool {
+	return slices.Contains(c.Permissions, permission)
+}
+
+// IsOAuth reports whether the token was issued to a third-party OAuth client
+// rather than to the user directly.
+func (c *Claims) IsOAuth() bool {
+	return c.ClientID != ""
+}
 
-	claims := jwt.MapClaims{
-		"userID": userID,
-		"role":   role,
-		"exp":    time.Now().Add(24 * time.Hour).Unix(),
+type KeyLookup func(kid string) (*SigningKey, bool)
+
+// GenerateToken signs claims as an access token. The registered claims (jti,
+// iat, exp) are always set here and override whatever the caller passed.
+func GenerateToken(key *SigningKey, claims Claims) (string, error) {
+	jti, err := token.Generate()
+	if err != nil {
+		return "", err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	now := time.Now()
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        jti,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(consts.AccessTokenTTL)),
+	}
+
+	method := key.method()
+	if method == nil {
+		return "", ErrUnsupportedAlgorithm
+	}
 
-	return token.SignedString([]byte(secret))
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.ID
+
+	return token.SignedString(key.PrivateKey)
+
+}
+
+func ParseToken(tokenStr string, lookup KeyLookup) (*Claims, error) {
+	claims := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+
+		key, ok := lookup(kid)
+		if !ok || key.Algorithm != t.Method.Alg() {
+			return nil, consts.ErrInvalidToken
+		}
+
+		return key.PrivateKey.Public(), nil
+	},
+		jwt.WithValidMethods([]string{AlgEdDSA, AlgRS256}),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+
+	if err != nil || !token.Valid || claims.ID == "" || claims.IssuedAt == nil {
+		return nil, consts.ErrInvalidToken
+	}
 
+	return claims, nil
 }
@@ -0,0 +1,156 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+var ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+
+type SigningKey struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewSigningKey(algorithm string) (*SigningKey, error) {
+	var (
+		privateKey crypto.Signer
+		err        error
+	)
+
+	switch algorithm {
+	case AlgEdDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	case AlgRS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	kid := make([]byte, 8)
+
+	_, err = rand.Read(kid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{
+		ID:         hex.EncodeToString(kid),
+		Algorithm:  algorithm,
+		PrivateKey: privateKey,
+	}, nil
+}
+
+func ParseSigningKey(kid string, algorithm string, privateKeyPEM string) (*SigningKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil {
+		return nil, errors.New("invalid private key PEM")
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	key := &SigningKey{
+		ID:         kid,
+		Algorithm:  algorithm,
+		PrivateKey: privateKey,
+	}
+
+	if key.method() == nil {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	return key, nil
+}
+
+func (k *SigningKey) MarshalPrivateKey() (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func (k *SigningKey) JWK() JWK {
+	jwk := JWK{
+		Kid: k.ID,
+		Alg: k.Algorithm,
+		Use: "sig",
+	}
+
+	switch public := k.PrivateKey.Public().(type) {
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	}
+
+	return jwk
+}
+
+func (k *SigningKey) method() jwt.SigningMethod {
+	switch {
+	case k.Algorithm == AlgEdDSA && isEd25519(k.PrivateKey):
+		return jwt.SigningMethodEdDSA
+	case k.Algorithm == AlgRS256 && isRSA(k.PrivateKey):
+		return jwt.SigningMethodRS256
+	}
+	return nil
+}
+
+func isEd25519(key crypto.Signer) bool {
+	_, ok := key.(ed25519.PrivateKey)
+	return ok
+}
+
+func isRSA(key crypto.Signer) bool {
+	_, ok := key.(*rsa.PrivateKey)
+	return ok
+}
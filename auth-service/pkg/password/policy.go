@@ -0,0 +1,86 @@
+package password
+
+import (
+	"auth-service/pkg/consts"
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+//go:embed common-passwords.txt
+var commonPasswords string
+
+// Policy decides which new passwords are acceptable. Length is counted in
+// characters, not bytes, so passphrases in any script are treated equally.
+type Policy struct {
+	MinLength int
+	MaxLength int
+	denylist  map[string]struct{}
+}
+
+// NewPolicy builds a policy with the built-in list of common passwords and,
+// if denylistPath is set, the passwords from that file, one per line.
+func NewPolicy(minLength int, maxLength int, denylistPath string) (*Policy, error) {
+	p := &Policy{
+		MinLength: minLength,
+		MaxLength: maxLength,
+		denylist:  make(map[string]struct{}),
+	}
+
+	err := p.load(strings.NewReader(commonPasswords))
+	if err != nil {
+		return nil, err
+	}
+
+	if denylistPath != "" {
+		file, err := os.Open(denylistPath)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		err = p.load(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func (p *Policy) load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.denylist[strings.ToLower(line)] = struct{}{}
+	}
+
+	return scanner.Err()
+}
+
+// Check returns an error wrapping consts.ErrWeakPassword that says what is
+// wrong with password, or nil if it is acceptable.
+func (p *Policy) Check(password string) error {
+	length := utf8.RuneCountInString(password)
+
+	if length < p.MinLength {
+		return fmt.Errorf("%w: must be at least %d characters long", consts.ErrWeakPassword, p.MinLength)
+	}
+
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("%w: must be at most %d characters long", consts.ErrWeakPassword, p.MaxLength)
+	}
+
+	if _, ok := p.denylist[strings.ToLower(password)]; ok {
+		return fmt.Errorf("%w: too common", consts.ErrWeakPassword)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of the RFC 6238 test vectors,
+// "12345678901234567890" in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B lists 8 digit codes, Code returns their last 6.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	_, err := Code("not base32!", 1)
+	if err == nil {
+		t.Fatal("Code accepted a secret that is not base32")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+
+	code := func(step int64) string {
+		c, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{name: "current step", secret: rfcSecret, code: code(current), wantStep: current, wantOK: true},
+		{name: "previous step within skew", secret: rfcSecret, code: code(current - 1), wantStep: current - 1, wantOK: true},
+		{name: "next step within skew", secret: rfcSecret, code: code(current + 1), wantStep: current + 1, wantOK: true},
+		{name: "two steps back", secret: rfcSecret, code: code(current - 2)},
+		{name: "two steps ahead", secret: rfcSecret, code: code(current + 2)},
+		{name: "surrounding spaces", secret: rfcSecret, code: " " + code(current) + " ", wantStep: current, wantOK: true},
+		{name: "lowercase secret", secret: "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", code: code(current), wantStep: current, wantOK: true},
+		{name: "too short", secret: rfcSecret, code: code(current)[:5]},
+		{name: "too long", secret: rfcSecret, code: code(current) + "0"},
+		{name: "empty", secret: rfcSecret, code: ""},
+		{name: "invalid secret", secret: "not base32!", code: code(current)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(tt.secret, tt.code, now)
+			if ok != tt.wantOK {
+				t.Fatalf("Validate ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != tt.wantStep {
+				t.Errorf("Validate step = %d, want %d", step, tt.wantStep)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+CREATE TABLE IF NOT EXISTS roles (
+    name VARCHAR(20) PRIMARY KEY CHECK(LENGTH(TRIM(name)) >= 1),
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+    name VARCHAR(50) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(20) NOT NULL REFERENCES roles(name) ON UPDATE CASCADE ON DELETE CASCADE,
+    permission VARCHAR(50) NOT NULL REFERENCES permissions(name) ON UPDATE CASCADE ON DELETE CASCADE,
+    PRIMARY KEY (role, permission)
+);
+
+INSERT INTO roles (name, description) VALUES
+    ('user', 'Read-only access to movies and actors'),
+    ('editor', 'Creates and updates movies and actors'),
+    ('admin', 'Full access, including user management')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO permissions (name, description) VALUES
+    ('movies:read', 'List and search movies'),
+    ('movies:write', 'Create and update movies'),
+    ('movies:delete', 'Delete movies'),
+    ('actors:read', 'List actors'),
+    ('actors:write', 'Create and update actors'),
+    ('actors:delete', 'Delete actors'),
+    ('users:manage', 'Manage users, invitations and service accounts'),
+    ('keys:manage', 'Rotate JWT signing keys')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role, permission) VALUES
+    ('user', 'movies:read'),
+    ('user', 'actors:read'),
+    ('editor', 'movies:read'),
+    ('editor', 'movies:write'),
+    ('editor', 'actors:read'),
+    ('editor', 'actors:write'),
+    ('admin', 'movies:read'),
+    ('admin', 'movies:write'),
+    ('admin', 'movies:delete'),
+    ('admin', 'actors:read'),
+    ('admin', 'actors:write'),
+    ('admin', 'actors:delete'),
+    ('admin', 'users:manage'),
+    ('admin', 'keys:manage')
+ON CONFLICT DO NOTHING;
+
+-- Roles move from a fixed CHECK list to the roles table.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE invitations DROP CONSTRAINT IF EXISTS invitations_role_check;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'users_role_fkey') THEN
+        ALTER TABLE users ADD CONSTRAINT users_role_fkey
+            FOREIGN KEY (role) REFERENCES roles(name) ON UPDATE CASCADE;
+    END IF;
+
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'invitations_role_fkey') THEN
+        ALTER TABLE invitations ADD CONSTRAINT invitations_role_fkey
+            FOREIGN KEY (role) REFERENCES roles(name) ON UPDATE CASCADE ON DELETE CASCADE;
+    END IF;
+END
+$$;
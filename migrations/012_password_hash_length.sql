@@ -0,0 +1,2 @@
+-- argon2id PHC strings are longer than the bcrypt hashes the column was sized for.
+ALTER TABLE users ALTER COLUMN password_hash TYPE VARCHAR(255);
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+-- Refresh token families become sessions. Tokens issued before sessions
+-- existed have none and cannot be rotated any more, their users log in again.
+DELETE FROM refresh_tokens WHERE family_id NOT IN (SELECT id FROM sessions);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'refresh_tokens_family_id_fkey') THEN
+        ALTER TABLE refresh_tokens ADD CONSTRAINT refresh_tokens_family_id_fkey
+            FOREIGN KEY (family_id) REFERENCES sessions(id) ON DELETE CASCADE;
+    END IF;
+END
+$$;
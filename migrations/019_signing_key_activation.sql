@@ -0,0 +1,4 @@
+-- A rotated key is published in the JWKS for a while before it signs tokens,
+-- so verifiers know it by the time they see it.
+ALTER TABLE signing_keys ADD COLUMN IF NOT EXISTS activates_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+UPDATE signing_keys SET activates_at = created_at;
@@ -0,0 +1,33 @@
+#!/bin/sh
+# Brings the database to the current schema: create.sql first, then every
+# numbered migration that schema_migrations does not list yet, in order, each
+# in its own transaction. Migrations only add to the schema and tolerate
+# objects that already exist, so databases created by an older create.sql are
+# upgraded as well. Connection settings come from the PG* variables.
+#
+# The roles of api-gateway and the catalog services are then (re)created with
+# GATEWAY_DB_PASSWORD and CATALOG_DB_PASSWORD and granted their tables.
+set -eu
+
+cd "$(dirname "$0")"
+
+run() {
+    psql -v ON_ERROR_STOP=1 -q "$@"
+}
+
+run -f create.sql
+run -c "CREATE TABLE IF NOT EXISTS schema_migrations (version TEXT PRIMARY KEY, applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW())"
+
+for file in [0-9]*.sql; do
+    version="${file%.sql}"
+
+    applied=$(run -tA -c "SELECT 1 FROM schema_migrations WHERE version = '$version'")
+    if [ -n "$applied" ]; then
+        continue
+    fi
+
+    echo "Applying $file"
+    run -1 -f "$file" -c "INSERT INTO schema_migrations (version) VALUES ('$version')"
+done
+
+run -v gateway_password="$GATEWAY_DB_PASSWORD" -v catalog_password="$CATALOG_DB_PASSWORD" -f roles.sql
@@ -0,0 +1,31 @@
+-- Database roles of the services other than auth-service. They are granted
+-- only the tables and columns they query: signing_keys, password and secret
+-- hashes and the rest of the auth schema stay readable by the owner role that
+-- auth-service uses. Run by migrate.sh after the migrations with the
+-- passwords as psql variables.
+
+SELECT format('CREATE ROLE gateway LOGIN PASSWORD %L', :'gateway_password')
+WHERE NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'gateway')
+\gexec
+
+SELECT format('CREATE ROLE catalog LOGIN PASSWORD %L', :'catalog_password')
+WHERE NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'catalog')
+\gexec
+
+ALTER ROLE gateway LOGIN PASSWORD :'gateway_password';
+ALTER ROLE catalog LOGIN PASSWORD :'catalog_password';
+
+REVOKE ALL ON ALL TABLES IN SCHEMA public FROM gateway, catalog;
+REVOKE ALL ON ALL SEQUENCES IN SCHEMA public FROM gateway, catalog;
+
+-- api-gateway: token checks, API keys, permissions and rate limiting.
+GRANT SELECT (id, role, disabled_at, tokens_revoked_at) ON users TO gateway;
+GRANT SELECT (id, revoked_at) ON sessions TO gateway;
+GRANT SELECT (id, revoked_at) ON oauth_clients TO gateway;
+GRANT SELECT (key_hash, user_id, scopes, expires_at, revoked_at) ON api_keys TO gateway;
+GRANT SELECT ON revoked_tokens, role_permissions TO gateway;
+GRANT SELECT, INSERT, UPDATE, DELETE ON rate_limit_buckets TO gateway;
+
+-- movies-service and actors-service.
+GRANT SELECT, INSERT, UPDATE, DELETE ON movies, actors, movie_actors TO catalog;
+GRANT USAGE ON SEQUENCE movies_id_seq, actors_id_seq TO catalog;
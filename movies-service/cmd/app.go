@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"movies-service/internal/handler"
 	"movies-service/internal/postgres"
 	"movies-service/internal/repository"
 	"movies-service/internal/service"
+	"movies-service/pkg/health"
+	"movies-service/pkg/logger"
+	"movies-service/pkg/metrics"
+	"movies-service/pkg/tracing"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,13 +19,24 @@ import (
 )
 
 func Run() error {
+	logger.Setup("movies-service")
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "movies-service")
+	if err != nil {
+		return err
+	}
+
+	defer shutdownTracing(context.Background())
+
 	router := http.NewServeMux()
 	db, err := postgres.NewConnectDb()
 	if err != nil {
-		log.Panicln(err)
+		return err
 	}
 
-	log.Println("Postgres DB connected: ", db)
+	slog.Info("Postgres DB connected")
+
+	metrics.RegisterDB(db.DB, "postgres")
 
 	defer db.Close()
 
@@ -30,46 +45,52 @@ func Run() error {
 
 	handler.NewMovieHandler(router, movieService)
 
-	log.Println("Movie repository initialized:", movieRepository)
-	log.Println("Movie service initialized:", movieService)
+	health.Register(router, map[string]health.Check{"postgres": db.PingContext})
 
 	server := http.Server{
 		Addr:    ":8002",
-		Handler: router,
+		Handler: metrics.Instrument(router, logger.AccessLog(router, tracing.Handler("movies-service", router))),
 	}
+
+	metricsServer := metrics.Serve(os.Getenv("METRICS_ADDR"))
+
 	quit := make(chan os.Signal, 1)
 
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
-		log.Println("Movies microservice start on port 8002")
+		slog.Info("Movies microservice started", "addr", server.Addr)
 		err := server.ListenAndServe()
 		if err != nil && err != http.ErrServerClosed {
-			log.Fatalf("ListenAndServe error: %v", err)
+			slog.Error("ListenAndServe error", "error", err)
+			os.Exit(1)
 		}
 	}()
 
 	<-quit
 
-	log.Println("Shutting down movie microservice...")
+	slog.Info("Shutting down movie microservice")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 
 	defer cancel()
 
+	metricsServer.Shutdown(ctx)
+
 	err = server.Shutdown(ctx)
 
 	if err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		slog.Error("Server forced to shutdown", "error", err)
+		os.Exit(1)
 	}
 
 	err = db.Close()
 
 	if err != nil {
-		log.Printf("Error closing database: %v", err)
+		slog.Error("Error closing database", "error", err)
 	}
 
-	log.Println("Movie microservice stopped gracefully")
+	slog.Info("Movie microservice stopped gracefully")
 
 	return nil
 
@@ -78,6 +99,7 @@ func Run() error {
 func main() {
 	err := Run()
 	if err != nil {
-		log.Fatalf("Movie microservice failed: %v", err)
+		slog.Error("Movie microservice failed", "error", err)
+		os.Exit(1)
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"movies-service/internal/model"
 	"movies-service/internal/payload"
 	"movies-service/internal/repository"
+	"movies-service/pkg/metrics"
 )
 
 type MovieService struct {
@@ -22,6 +23,7 @@ func (s *MovieService) Create(ctx context.Context, p *payload.MoviePayload) (uin
 	if err != nil {
 		return 0, err
 	}
+	metrics.MoviesCreated.Inc()
 	return movieID, nil
 }
 
@@ -62,6 +64,7 @@ func (s *MovieService) Delete(ctx context.Context, id uint) error {
 	if err != nil {
 		return err
 	}
+	metrics.MoviesDeleted.Inc()
 	return nil
 }
 
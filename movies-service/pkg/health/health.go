@@ -0,0 +1,81 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"movies-service/pkg/res"
+	"net/http"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+
+	checkTimeout = 2 * time.Second
+)
+
+// Check reports whether a dependency of the service can be used.
+type Check func(ctx context.Context) error
+
+type Response struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// Register adds the probes to router. GET /healthz only tells that the
+// process serves requests. GET /readyz runs every check and answers 503 if
+// one of them fails, so traffic is held back until the dependencies are up.
+func Register(router *http.ServeMux, checks map[string]Check) {
+	router.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		res.ResJson(w, &Response{Status: StatusOK}, http.StatusOK)
+	})
+
+	router.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		resp := Run(r.Context(), checks)
+
+		status := http.StatusOK
+		if resp.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+
+		res.ResJson(w, resp, status)
+	})
+}
+
+// Run runs checks at once, each bounded by its own timeout. Errors are logged,
+// not returned, since they may describe the internal network.
+func Run(ctx context.Context, checks map[string]Check) *Response {
+	type result struct {
+		name string
+		err  error
+	}
+
+	results := make(chan result, len(checks))
+
+	for name, check := range checks {
+		go func() {
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+
+			results <- result{name, check(ctx)}
+		}()
+	}
+
+	resp := &Response{Status: StatusOK, Checks: make(map[string]string, len(checks))}
+
+	for range checks {
+		result := <-results
+
+		if result.err != nil {
+			slog.WarnContext(ctx, "Health check failed", "check", result.name, "error", result.err)
+			resp.Status = StatusUnavailable
+			resp.Checks[result.name] = StatusUnavailable
+			continue
+		}
+
+		resp.Checks[result.name] = StatusOK
+	}
+
+	return resp
+}